@@ -1,7 +1,18 @@
 package id
 
-import "github.com/oklog/ulid/v2"
+import (
+	"io"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
 
 func New() string {
 	return ulid.Make().String()
 }
+
+// NewAt returns an ID timestamped at t with its random part drawn from
+// entropy, so a seeded clock and source produce reproducible IDs.
+func NewAt(t time.Time, entropy io.Reader) string {
+	return ulid.MustNew(ulid.Timestamp(t), entropy).String()
+}
@@ -0,0 +1,103 @@
+package random
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Epochs fall within ten years of the base, well inside the range of ULID
+// timestamps.
+var (
+	epochBase = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	epochSpan = 10 * 365 * 24 * time.Hour
+)
+
+// Rand is a seeded random source safe for concurrent use, so a single source
+// can be shared across goroutines and a run replayed from its seed.
+type Rand struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+	seed int64
+}
+
+// New returns a source seeded with seed, or with the current time when seed
+// is zero. Seed reports the seed in use either way.
+func New(seed int64) *Rand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return &Rand{
+		rand: rand.New(rand.NewSource(seed)),
+		seed: seed,
+	}
+}
+
+func (r *Rand) Seed() int64 {
+	return r.seed
+}
+
+// Derive returns an independent source for stream, seeded from this source's
+// seed alone. Goroutines that each draw from their own derived source replay
+// the same sequences however they are scheduled.
+func (r *Rand) Derive(stream int64) *Rand {
+	seed := int64(mix(uint64(r.seed) + uint64(stream)*0x9e3779b97f4a7c15))
+	if seed == 0 {
+		seed = 1
+	}
+
+	return &Rand{
+		rand: rand.New(rand.NewSource(seed)),
+		seed: seed,
+	}
+}
+
+// Epoch returns a fixed instant derived from the seed, standing in for the
+// current time wherever a run must be reproducible, such as ULID timestamps.
+func (r *Rand) Epoch() time.Time {
+	offset := mix(uint64(r.seed)) % uint64(epochSpan/time.Millisecond)
+
+	return epochBase.Add(time.Duration(offset) * time.Millisecond)
+}
+
+func (r *Rand) Intn(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.rand.Intn(n)
+}
+
+func (r *Rand) Int63n(n int64) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.rand.Int63n(n)
+}
+
+func (r *Rand) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.rand.Float64()
+}
+
+// Read fills p with random bytes, letting the source serve as entropy for
+// ULIDs and similar identifiers.
+func (r *Rand) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.rand.Read(p)
+}
+
+// mix is the splitmix64 finalizer, spreading nearby seeds far apart.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x
+}
@@ -0,0 +1,100 @@
+package random
+
+import (
+	"sync"
+	"testing"
+)
+
+func draws(r *Rand, n int) []int64 {
+	values := make([]int64, n)
+	for i := range values {
+		values[i] = r.Int63n(1 << 40)
+	}
+
+	return values
+}
+
+func equal(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestDeriveIsReproducible(t *testing.T) {
+	a := draws(New(42).Derive(3), 10)
+	b := draws(New(42).Derive(3), 10)
+
+	if !equal(a, b) {
+		t.Fatalf("streams derived from the same seed differ: %v and %v", a, b)
+	}
+
+	if other := draws(New(42).Derive(4), 10); equal(a, other) {
+		t.Fatalf("streams 3 and 4 drew the same values %v", a)
+	}
+
+	if other := draws(New(43).Derive(3), 10); equal(a, other) {
+		t.Fatalf("seeds 42 and 43 derived the same stream %v", a)
+	}
+}
+
+func TestDeriveIgnoresParentDraws(t *testing.T) {
+	used := New(42)
+	draws(used, 5)
+
+	if a, b := draws(used.Derive(1), 10), draws(New(42).Derive(1), 10); !equal(a, b) {
+		t.Fatalf("derived stream depends on the parent's draws: %v and %v", a, b)
+	}
+}
+
+func TestDerivedStreamsAreIndependentOfScheduling(t *testing.T) {
+	run := func() [][]int64 {
+		rng := New(7)
+		streams := make([][]int64, 8)
+
+		var wg sync.WaitGroup
+
+		for i := range streams {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+				streams[i] = draws(rng.Derive(int64(i)), 100)
+			}()
+		}
+
+		wg.Wait()
+
+		return streams
+	}
+
+	first, second := run(), run()
+
+	for i := range first {
+		if !equal(first[i], second[i]) {
+			t.Fatalf("stream %d differs between runs", i)
+		}
+	}
+}
+
+func TestEpoch(t *testing.T) {
+	a, b := New(42).Epoch(), New(42).Epoch()
+	if !a.Equal(b) {
+		t.Fatalf("Epoch() = %s and %s for the same seed", a, b)
+	}
+
+	if a.Before(epochBase) || !a.Before(epochBase.Add(epochSpan)) {
+		t.Fatalf("Epoch() = %s, want within %s of %s", a, epochSpan, epochBase)
+	}
+
+	if c := New(43).Epoch(); a.Equal(c) {
+		t.Fatalf("seeds 42 and 43 share the epoch %s", a)
+	}
+}
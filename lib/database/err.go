@@ -3,6 +3,8 @@ package database
 import "errors"
 
 var (
-	ErrPreparation          = errors.New("unable to prepare the query")
-	ErrStatementNotPrepared = errors.New("statement not prepared")
+	ErrPreparation           = errors.New("unable to prepare the query")
+	ErrStatementNotPrepared  = errors.New("statement not prepared")
+	ErrInvalidMigration      = errors.New("invalid migration")
+	ErrIrreversibleMigration = errors.New("migration has no down script")
 )
@@ -0,0 +1,296 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/observability"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	migrationsTable = "schema_migrations"
+
+	// migrationLockID is the key of the Postgres advisory lock held while
+	// migrations run, so concurrent instances apply them only once.
+	migrationLockID int64 = 4_270_331_917
+)
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies versioned SQL migrations read from files named
+// <version>_<name>.up.sql and <version>_<name>.down.sql, recording applied
+// versions in the schema_migrations table.
+type Migrator struct {
+	db         *sqlx.DB
+	logger     observability.Logger
+	migrations []Migration
+}
+
+func NewMigrator(db *sqlx.DB, logger observability.Logger, migrations fs.FS) (*Migrator, error) {
+	parsed, err := parseMigrations(migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		logger:     logger,
+		migrations: parsed,
+	}, nil
+}
+
+func parseMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMigration, entry.Name())
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("%w: version %d has conflicting names %q and %q", ErrInvalidMigration, version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("%w: version %d has no up script", ErrInvalidMigration, m.Version)
+		}
+
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up applies every pending migration in version order.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		pending := 0
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			if err := m.apply(ctx, conn, migration, migration.Up,
+				`INSERT INTO `+migrationsTable+` (version, name) VALUES ($1, $2)`,
+				migration.Version, migration.Name,
+			); err != nil {
+				return err
+			}
+
+			m.logger.Info("Applied migration", "version", migration.Version, "name", migration.Name)
+			pending++
+		}
+
+		if pending == 0 {
+			m.logger.Info("No pending migrations")
+		}
+
+		return nil
+	})
+}
+
+// Down reverts the most recently applied migrations, at most steps of them.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			migration := m.migrations[i]
+
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+
+			if migration.Down == "" {
+				return fmt.Errorf("%w: version %d", ErrIrreversibleMigration, migration.Version)
+			}
+
+			if err := m.apply(ctx, conn, migration, migration.Down,
+				`DELETE FROM `+migrationsTable+` WHERE version = $1`,
+				migration.Version,
+			); err != nil {
+				return err
+			}
+
+			m.logger.Info("Reverted migration", "version", migration.Version, "name", migration.Name)
+			steps--
+		}
+
+		return nil
+	})
+}
+
+// Status reports every known migration and whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+
+	err := m.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		statuses = make([]MigrationStatus, 0, len(m.migrations))
+		for _, migration := range m.migrations {
+			appliedAt, ok := applied[migration.Version]
+
+			statuses = append(statuses, MigrationStatus{
+				Migration: migration,
+				Applied:   ok,
+				AppliedAt: appliedAt,
+			})
+		}
+
+		return nil
+	})
+
+	return statuses, err
+}
+
+// apply runs a migration script and its bookkeeping statement in a single
+// transaction, so a failed script leaves no partial record behind.
+func (m *Migrator) apply(ctx context.Context, conn *sqlx.Conn, migration Migration, script, record string, args ...any) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration transaction: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("failed to run migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("failed to record migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	return nil
+}
+
+// withLock runs fn on a dedicated connection holding the migration advisory
+// lock. Advisory locks are session scoped, so every statement must go through
+// the same connection.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sqlx.Conn) error) error {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+
+	defer func() {
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, migrationLockID); err != nil {
+			m.logger.Error(fmt.Sprintf("failed to release migration lock: %v", err))
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+migrationsTable+` (
+		version bigint PRIMARY KEY NOT NULL,
+		name varchar NOT NULL,
+		applied_at timestamp DEFAULT now() NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	return fn(conn)
+}
+
+func appliedMigrations(ctx context.Context, conn *sqlx.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryxContext(ctx, `SELECT version, applied_at FROM `+migrationsTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+
+	for rows.Next() {
+		var (
+			version   int64
+			appliedAt time.Time
+		)
+
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
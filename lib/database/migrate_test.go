@@ -0,0 +1,184 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Warn(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
+
+var testMigrations = fstest.MapFS{
+	"000010_adds_index.up.sql":       {Data: []byte("CREATE INDEX idx ON routines (id)")},
+	"000002_adds_column.up.sql":      {Data: []byte("ALTER TABLE routines ADD COLUMN area varchar")},
+	"000002_adds_column.down.sql":    {Data: []byte("ALTER TABLE routines DROP COLUMN area")},
+	"000001_creates_table.up.sql":    {Data: []byte("CREATE TABLE routines (id varchar)")},
+	"000001_creates_table.down.sql":  {Data: []byte("DROP TABLE routines")},
+	"README.md":                      {Data: []byte("not a migration")},
+	"000003_ignored/nested.up.sql":   {Data: []byte("SELECT 1")},
+	"000004_missing_extension.up.sq": {Data: []byte("SELECT 1")},
+}
+
+func TestParseMigrationsOrdersByVersion(t *testing.T) {
+	migrations, err := parseMigrations(testMigrations)
+	if err != nil {
+		t.Fatalf("parseMigrations: %v", err)
+	}
+
+	want := []struct {
+		version int64
+		name    string
+		down    bool
+	}{
+		{1, "creates_table", true},
+		{2, "adds_column", true},
+		{10, "adds_index", false},
+	}
+
+	if len(migrations) != len(want) {
+		t.Fatalf("parsed %d migrations, want %d", len(migrations), len(want))
+	}
+
+	for i, w := range want {
+		m := migrations[i]
+
+		if m.Version != w.version || m.Name != w.name || (m.Down != "") != w.down {
+			t.Fatalf("migrations[%d] = %d_%s (down %t), want %d_%s (down %t)",
+				i, m.Version, m.Name, m.Down != "", w.version, w.name, w.down)
+		}
+	}
+}
+
+func TestParseMigrationsRejectsInvalidSets(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"missing up script": {
+			"000001_creates_table.down.sql": {Data: []byte("DROP TABLE routines")},
+		},
+		"conflicting names": {
+			"000001_creates_table.up.sql":   {Data: []byte("CREATE TABLE routines (id varchar)")},
+			"000001_creates_other.down.sql": {Data: []byte("DROP TABLE other")},
+		},
+	}
+
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseMigrations(fsys); !errors.Is(err, ErrInvalidMigration) {
+				t.Fatalf("parseMigrations error = %v, want %v", err, ErrInvalidMigration)
+			}
+		})
+	}
+}
+
+func newTestMigrator(t *testing.T) (*Migrator, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	m, err := NewMigrator(sqlx.NewDb(db, "postgres"), nopLogger{}, testMigrations)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+
+	return m, mock
+}
+
+func expectLockAndApplied(mock sqlmock.Sqlmock, applied ...int64) {
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_lock($1)`)).
+		WithArgs(migrationLockID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS ` + migrationsTable).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	rows := sqlmock.NewRows([]string{"version", "applied_at"})
+	for _, version := range applied {
+		rows.AddRow(version, time.Now())
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT version, applied_at FROM ` + migrationsTable)).
+		WillReturnRows(rows)
+}
+
+func expectUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_unlock($1)`)).
+		WithArgs(migrationLockID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func expectApply(mock sqlmock.Sqlmock, script string, version int64, name string) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(script)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO `+migrationsTable)).
+		WithArgs(version, name).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func TestUpAppliesPendingMigrationsInOrder(t *testing.T) {
+	m, mock := newTestMigrator(t)
+
+	expectLockAndApplied(mock)
+	expectApply(mock, "CREATE TABLE routines (id varchar)", 1, "creates_table")
+	expectApply(mock, "ALTER TABLE routines ADD COLUMN area varchar", 2, "adds_column")
+	expectApply(mock, "CREATE INDEX idx ON routines (id)", 10, "adds_index")
+	expectUnlock(mock)
+
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpSkipsAppliedMigrations(t *testing.T) {
+	m, mock := newTestMigrator(t)
+
+	expectLockAndApplied(mock, 1, 2)
+	expectApply(mock, "CREATE INDEX idx ON routines (id)", 10, "adds_index")
+	expectUnlock(mock)
+
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpStopsAtFailedMigration(t *testing.T) {
+	m, mock := newTestMigrator(t)
+
+	scriptErr := errors.New("syntax error")
+
+	expectLockAndApplied(mock, 1)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE routines ADD COLUMN area varchar")).WillReturnError(scriptErr)
+	mock.ExpectRollback()
+	expectUnlock(mock)
+
+	if err := m.Up(context.Background()); !errors.Is(err, scriptErr) {
+		t.Fatalf("Up error = %v, want %v", err, scriptErr)
+	}
+
+	// the failed migration is not recorded and later ones are not attempted
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,95 @@
+package capture
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/proto/gen/pb"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Writer appends routines to a capture, a sequence of varint length-delimited
+// pb.CapturedRoutine messages each holding a routine and the time it was
+// seen, so the stream can later be replayed exactly. It is safe for
+// concurrent use.
+type Writer struct {
+	mu     sync.Mutex
+	w      *bufio.Writer
+	closer io.Closer
+}
+
+// NewWriter returns a writer appending to w. When w is also an io.Closer,
+// Close closes it after flushing.
+func NewWriter(w io.Writer) *Writer {
+	closer, _ := w.(io.Closer)
+
+	return &Writer{
+		w:      bufio.NewWriter(w),
+		closer: closer,
+	}
+}
+
+func (w *Writer) Write(capturedAt time.Time, routine *pb.DeviceRoutine) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, err := protodelim.MarshalTo(w.w, &pb.CapturedRoutine{
+		CapturedAt: timestamppb.New(capturedAt),
+		Routine:    routine,
+	}); err != nil {
+		return fmt.Errorf("failed to write captured routine: %w", err)
+	}
+
+	return nil
+}
+
+// Flush writes buffered routines to the underlying writer.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.w.Flush()
+}
+
+// Close flushes buffered routines and closes the underlying writer.
+func (w *Writer) Close() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if w.closer == nil {
+		return nil
+	}
+
+	return w.closer.Close()
+}
+
+// Reader reads routines back from a capture in the order they were written.
+type Reader struct {
+	r *bufio.Reader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next captured routine, or io.EOF once the capture is
+// exhausted.
+func (r *Reader) Next() (*pb.CapturedRoutine, error) {
+	var captured pb.CapturedRoutine
+
+	if err := protodelim.UnmarshalFrom(r.r, &captured); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+
+		return nil, fmt.Errorf("failed to read captured routine: %w", err)
+	}
+
+	return &captured, nil
+}
@@ -0,0 +1,170 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/messaging"
+	"github.com/charmingruby/devicio/lib/proto/gen/pb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func routine(id string, status pb.DeviceStatus) *pb.DeviceRoutine {
+	return &pb.DeviceRoutine{
+		Id:           id,
+		Status:       status,
+		Context:      "routine",
+		Diagnostics:  "Flow rate stable",
+		Area:         "A",
+		DispatchedAt: timestamppb.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)),
+	}
+}
+
+// readAll reads every captured routine until the capture is exhausted.
+func readAll(t *testing.T, r io.Reader) []*pb.CapturedRoutine {
+	t.Helper()
+
+	reader := NewReader(r)
+
+	var captured []*pb.CapturedRoutine
+
+	for {
+		c, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return captured
+		}
+
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+
+		captured = append(captured, c)
+	}
+}
+
+func TestWriterReaderRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	want := []struct {
+		at      time.Time
+		routine *pb.DeviceRoutine
+	}{
+		{at: base, routine: routine("device-1", pb.DeviceStatus_HEALTHY)},
+		{at: base.Add(1500 * time.Millisecond), routine: routine("device-2", pb.DeviceStatus_CRITICAL)},
+		{at: base.Add(3 * time.Second), routine: routine("device-1", pb.DeviceStatus_WARNING)},
+	}
+
+	w := NewWriter(&buf)
+
+	for _, c := range want {
+		if err := w.Write(c.at, c.routine); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	if buf.Len() != 0 {
+		t.Fatalf("buffer holds %d bytes before Close, want writes to be buffered", buf.Len())
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	got := readAll(t, &buf)
+	if len(got) != len(want) {
+		t.Fatalf("read %d routines, want %d", len(got), len(want))
+	}
+
+	for i := range want {
+		if at := got[i].GetCapturedAt().AsTime(); !at.Equal(want[i].at) {
+			t.Fatalf("routine %d captured at %s, want %s", i, at, want[i].at)
+		}
+
+		if !proto.Equal(got[i].GetRoutine(), want[i].routine) {
+			t.Fatalf("routine %d = %v, want %v", i, got[i].GetRoutine(), want[i].routine)
+		}
+	}
+}
+
+func TestReaderRejectsTruncatedCapture(t *testing.T) {
+	var buf bytes.Buffer
+
+	w := NewWriter(&buf)
+	if err := w.Write(time.Now(), routine("device-1", pb.DeviceStatus_HEALTHY)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	_, err := NewReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1])).Next()
+	if err == nil || errors.Is(err, io.EOF) {
+		t.Fatalf("Next error = %v, want a read failure", err)
+	}
+}
+
+func newMessage(t *testing.T, msg proto.Message) messaging.Message {
+	t.Helper()
+
+	m, err := messaging.NewMessage(msg, "test")
+	if err != nil {
+		t.Fatalf("NewMessage: %v", err)
+	}
+
+	return m
+}
+
+func TestHandlerRecordsRoutinesAndSkipsOtherTypes(t *testing.T) {
+	var buf bytes.Buffer
+
+	w := NewWriter(&buf)
+	handle := Handler(w)
+
+	published := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	routineMsg := newMessage(t, routine("device-1", pb.DeviceStatus_ERROR))
+	routineMsg.PublishedAt = published
+
+	// a message of another type is skipped even though its body would not
+	// unmarshal as a routine
+	otherMsg := newMessage(t, &pb.CapturedRoutine{CapturedAt: timestamppb.Now()})
+	otherMsg.Body = []byte{0xff}
+
+	// messages from producers that predate the envelope carry no type
+	untypedMsg := newMessage(t, routine("device-2", pb.DeviceStatus_HEALTHY))
+	untypedMsg.Type = ""
+
+	for _, msg := range []messaging.Message{routineMsg, otherMsg, untypedMsg} {
+		if err := handle(context.Background(), msg); err != nil {
+			t.Fatalf("handling %s message: %v", msg.Type, err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	got := readAll(t, &buf)
+	if len(got) != 2 {
+		t.Fatalf("captured %d routines, want 2", len(got))
+	}
+
+	if id := got[0].GetRoutine().GetId(); id != "device-1" {
+		t.Fatalf("first captured routine = %s, want device-1", id)
+	}
+
+	if at := got[0].GetCapturedAt().AsTime(); !at.Equal(published) {
+		t.Fatalf("captured at %s, want the publish time %s", at, published)
+	}
+
+	if id := got[1].GetRoutine().GetId(); id != "device-2" {
+		t.Fatalf("second captured routine = %s, want device-2", id)
+	}
+}
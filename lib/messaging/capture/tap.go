@@ -0,0 +1,78 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/messaging"
+	"github.com/charmingruby/devicio/lib/observability"
+	"github.com/charmingruby/devicio/lib/proto/gen/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+var routineType = string(proto.MessageName(&pb.DeviceRoutine{}))
+
+// Tap is a messaging.Queue that records every routine it successfully
+// publishes. Other messages pass through unrecorded. A failure to record is
+// logged rather than returned, since the routine has already been published.
+type Tap struct {
+	messaging.Queue
+	logger observability.Logger
+	writer *Writer
+}
+
+func NewTap(logger observability.Logger, queue messaging.Queue, writer *Writer) *Tap {
+	return &Tap{
+		Queue:  queue,
+		logger: logger,
+		writer: writer,
+	}
+}
+
+func (t *Tap) Publish(ctx context.Context, msg proto.Message) (context.Context, error) {
+	ctx, err := t.Queue.Publish(ctx, msg)
+	if err != nil {
+		return ctx, err
+	}
+
+	if routine, ok := msg.(*pb.DeviceRoutine); ok {
+		if err := t.writer.Write(time.Now().UTC(), routine); err != nil {
+			t.logger.Error(fmt.Sprintf("failed to capture published routine: %v", err))
+		}
+	}
+
+	return ctx, nil
+}
+
+// Close closes the wrapped queue, then flushes and closes the capture.
+func (t *Tap) Close() {
+	t.Queue.Close()
+
+	if err := t.writer.Close(); err != nil {
+		t.logger.Error(fmt.Sprintf("failed to close capture: %v", err))
+	}
+}
+
+// Handler returns a messaging.Handler that records consumed routines, stamped
+// with the time they were published when the envelope carries it. Messages
+// of other types are skipped.
+func Handler(writer *Writer) messaging.Handler {
+	return func(_ context.Context, msg messaging.Message) error {
+		if msg.Type != "" && msg.Type != routineType {
+			return nil
+		}
+
+		var routine pb.DeviceRoutine
+		if err := msg.Unmarshal(&routine); err != nil {
+			return fmt.Errorf("failed to unmarshal routine: %w", err)
+		}
+
+		capturedAt := msg.PublishedAt
+		if capturedAt.IsZero() {
+			capturedAt = time.Now().UTC()
+		}
+
+		return writer.Write(capturedAt, &routine)
+	}
+}
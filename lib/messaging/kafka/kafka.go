@@ -0,0 +1,320 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/messaging"
+	"github.com/charmingruby/devicio/lib/observability"
+	kafkago "github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/proto"
+)
+
+const lastErrorHeader = "x-last-error"
+
+type Client struct {
+	writer     *kafkago.Writer
+	deadLetter *kafkago.Writer
+	cfg        *Config
+	logger     observability.Logger
+	tracer     observability.Tracer
+}
+
+type Config struct {
+	Brokers []string
+	Topic   string
+	GroupID string
+	// ServiceName is stamped as the producer of published messages.
+	ServiceName string
+	// MaxDeliveryAttempts bounds how many times a message is handled before
+	// it is dead-lettered. Defaults to 5.
+	MaxDeliveryAttempts int
+	// RetryBaseDelay is the delay before the first retry, doubled on each
+	// subsequent attempt. Defaults to 1s.
+	RetryBaseDelay time.Duration
+	// RetryMaxDelay caps the retry delay when set.
+	RetryMaxDelay time.Duration
+	// DeadLetterTopic defaults to "<Topic>.dead".
+	DeadLetterTopic string
+	// Concurrency is the number of group members started by Subscribe.
+	// Messages are handled in order within a partition. Defaults to 1.
+	Concurrency int
+	// DrainTimeout bounds how long Subscribe waits for in-flight handlers on
+	// shutdown. Defaults to 30s.
+	DrainTimeout time.Duration
+	// PartitionKey derives the message key from the payload, so that related
+	// messages land on the same partition and keep their order. Defaults to
+	// messaging.PartitionKey; messages without a key fall back to their ID.
+	PartitionKey func(msg proto.Message) string
+}
+
+func (c *Config) maxDeliveryAttempts() int {
+	if c.MaxDeliveryAttempts <= 0 {
+		return 5
+	}
+
+	return c.MaxDeliveryAttempts
+}
+
+func (c *Config) retryDelay(attempt int) time.Duration {
+	base := c.RetryBaseDelay
+	if base <= 0 {
+		base = time.Second
+	}
+
+	return messaging.RetryDelay(base, c.RetryMaxDelay, attempt)
+}
+
+func (c *Config) deadLetterTopic() string {
+	if c.DeadLetterTopic == "" {
+		return c.Topic + ".dead"
+	}
+
+	return c.DeadLetterTopic
+}
+
+func (c *Config) messageKey(msg proto.Message, m messaging.Message) []byte {
+	partitionKey := c.PartitionKey
+	if partitionKey == nil {
+		partitionKey = messaging.PartitionKey
+	}
+
+	if key := partitionKey(msg); key != "" {
+		return []byte(key)
+	}
+
+	return []byte(m.ID)
+}
+
+func (c *Config) concurrency() int {
+	if c.Concurrency <= 0 {
+		return 1
+	}
+
+	return c.Concurrency
+}
+
+func (c *Config) drainTimeout() time.Duration {
+	if c.DrainTimeout <= 0 {
+		return 30 * time.Second
+	}
+
+	return c.DrainTimeout
+}
+
+func New(logger observability.Logger, tracer observability.Tracer, cfg *Config) (*Client, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, errors.New("failed to connect to Kafka: no brokers configured")
+	}
+
+	return &Client{
+		writer:     newWriter(cfg.Brokers, cfg.Topic),
+		deadLetter: newWriter(cfg.Brokers, cfg.deadLetterTopic()),
+		cfg:        cfg,
+		logger:     logger,
+		tracer:     tracer,
+	}, nil
+}
+
+func newWriter(brokers []string, topic string) *kafkago.Writer {
+	return &kafkago.Writer{
+		Addr:                   kafkago.TCP(brokers...),
+		Topic:                  topic,
+		Balancer:               &kafkago.Hash{},
+		RequiredAcks:           kafkago.RequireAll,
+		AllowAutoTopicCreation: true,
+	}
+}
+
+func (c *Client) Close() {
+	if err := c.writer.Close(); err != nil {
+		c.logger.Warn("Failed to close Kafka writer", "error", err)
+	}
+
+	if err := c.deadLetter.Close(); err != nil {
+		c.logger.Warn("Failed to close Kafka dead-letter writer", "error", err)
+	}
+}
+
+func (c *Client) Publish(ctx context.Context, msg proto.Message) (context.Context, error) {
+	ctx, complete := c.tracer.Span(ctx, "kafka.Client.Publish")
+	defer complete()
+
+	m, err := messaging.NewMessage(msg, c.cfg.ServiceName)
+	if err != nil {
+		return ctx, err
+	}
+
+	err = c.writer.WriteMessages(ctx, kafkago.Message{
+		Key:     c.cfg.messageKey(msg, m),
+		Value:   m.Body,
+		Headers: c.injectHeaders(ctx, m.Headers()),
+	})
+	if err != nil {
+		return ctx, fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	return ctx, nil
+}
+
+// Subscribe joins the consumer group with Concurrency members and handles
+// messages until ctx is cancelled or a member fails, then waits up to
+// DrainTimeout for the in-flight handlers. A member failure stops the others
+// so that its error is returned promptly. Offsets are committed only after a
+// message is handled or dead-lettered.
+func (c *Client) Subscribe(ctx context.Context, handler messaging.Handler) error {
+	var wg sync.WaitGroup
+	errs := make(chan error, c.cfg.concurrency())
+
+	// members share a context cancelled by the first one to fail
+	membersCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for range c.cfg.concurrency() {
+		reader := kafkago.NewReader(kafkago.ReaderConfig{
+			Brokers: c.cfg.Brokers,
+			GroupID: c.cfg.GroupID,
+			Topic:   c.cfg.Topic,
+		})
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer reader.Close()
+
+			if err := c.consume(membersCtx, reader, handler); err != nil {
+				errs <- err
+				cancel()
+			}
+		}()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-membersCtx.Done():
+		c.logger.Info("Stopping Kafka consumers", "topic", c.cfg.Topic, "group", c.cfg.GroupID)
+
+		select {
+		case <-stopped:
+			c.logger.Info("Kafka consumers drained", "topic", c.cfg.Topic, "group", c.cfg.GroupID)
+		case <-time.After(c.cfg.drainTimeout()):
+			c.logger.Warn("Timed out draining in-flight messages", "topic", c.cfg.Topic, "timeout", c.cfg.drainTimeout().String())
+		}
+	}
+
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return nil
+	}
+}
+
+func (c *Client) consume(ctx context.Context, reader *kafkago.Reader, handler messaging.Handler) error {
+	// handlers and commits must be able to finish after ctx is cancelled,
+	// so they only inherit its values
+	handlerCtx := context.WithoutCancel(ctx)
+
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return fmt.Errorf("failed to fetch message: %w", err)
+		}
+
+		if !c.handleDelivery(ctx, handlerCtx, msg, handler) {
+			// shutting down mid-retry: leave the offset uncommitted so the
+			// message is redelivered
+			return nil
+		}
+
+		if err := reader.CommitMessages(handlerCtx, msg); err != nil {
+			c.logger.Error(fmt.Sprintf("failed to commit message: %v", err))
+		}
+	}
+}
+
+// handleDelivery runs the handler with in-process retries, since Kafka has
+// no per-message nack. It reports whether the offset can be committed.
+func (c *Client) handleDelivery(ctx, handlerCtx context.Context, msg kafkago.Message, handler messaging.Handler) bool {
+	headers := flattenHeaders(msg.Headers)
+	msgCtx := c.tracer.Extract(handlerCtx, headers)
+	m := messaging.MessageFromHeaders(headers, msg.Value)
+
+	for attempt := 1; ; attempt++ {
+		spanCtx, complete := c.tracer.Span(msgCtx, "kafka.Client.Subscribe.Handler")
+		err := handler(spanCtx, m)
+		complete()
+
+		if err == nil {
+			return true
+		}
+
+		c.logger.Error(fmt.Sprintf("failed to handle message: %v", err))
+
+		if attempt >= c.cfg.maxDeliveryAttempts() {
+			if err := c.deadLetterMessage(handlerCtx, msg, attempt, err); err != nil {
+				c.logger.Error(fmt.Sprintf("failed to dead-letter message: %v", err))
+				return false
+			}
+
+			return true
+		}
+
+		select {
+		case <-time.After(c.cfg.retryDelay(attempt)):
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+func (c *Client) deadLetterMessage(ctx context.Context, msg kafkago.Message, attempts int, handlerErr error) error {
+	c.logger.Warn("Delivery attempts exhausted, dead-lettering message",
+		"attempts", attempts,
+		"topic", c.cfg.deadLetterTopic(),
+	)
+
+	headers := append([]kafkago.Header{}, msg.Headers...)
+	headers = append(headers, kafkago.Header{Key: lastErrorHeader, Value: []byte(handlerErr.Error())})
+
+	return c.deadLetter.WriteMessages(ctx, kafkago.Message{
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	})
+}
+
+func (c *Client) injectHeaders(ctx context.Context, headers map[string]string) []kafkago.Header {
+	c.tracer.Inject(ctx, headers)
+
+	out := make([]kafkago.Header, 0, len(headers))
+	for k, v := range headers {
+		out = append(out, kafkago.Header{Key: k, Value: []byte(v)})
+	}
+
+	return out
+}
+
+func flattenHeaders(headers []kafkago.Header) map[string]string {
+	out := make(map[string]string, len(headers))
+
+	for _, h := range headers {
+		out[h.Key] = string(h.Value)
+	}
+
+	return out
+}
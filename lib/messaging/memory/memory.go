@@ -0,0 +1,364 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/messaging"
+	"github.com/charmingruby/devicio/lib/observability"
+	"google.golang.org/protobuf/proto"
+)
+
+var ErrClientClosed = errors.New("client closed")
+
+// Client is an in-process messaging.Queue. Messages live in memory only, so
+// publishers and subscribers must share the same Client.
+type Client struct {
+	mu       sync.Mutex
+	pending  []*Delivery
+	inFlight map[string]*Delivery
+	acked    []Delivery
+	dead     []Delivery
+	notify   chan struct{}
+	done     chan struct{}
+	closed   bool
+	cfg      *Config
+	logger   observability.Logger
+	tracer   observability.Tracer
+}
+
+type Config struct {
+	// ServiceName is stamped as the producer of published messages.
+	ServiceName string
+	// DeliveryDelay holds every published message back before it can be
+	// consumed, mimicking broker latency.
+	DeliveryDelay time.Duration
+	// MaxDeliveryAttempts bounds how many times a message is handled before
+	// it is dead-lettered. Defaults to 5.
+	MaxDeliveryAttempts int
+	// RetryBaseDelay delays redelivery of a nacked message, doubled on each
+	// subsequent attempt. Requeued messages are redelivered immediately when
+	// unset.
+	RetryBaseDelay time.Duration
+	// RetryMaxDelay caps the retry delay when set.
+	RetryMaxDelay time.Duration
+	// Concurrency is the number of handlers run in parallel per
+	// subscription. Defaults to 1.
+	Concurrency int
+	// DrainTimeout bounds how long Subscribe waits for in-flight handlers on
+	// shutdown. Defaults to 30s.
+	DrainTimeout time.Duration
+	// HistorySize bounds how many acked and dead-lettered deliveries are
+	// kept for introspection, dropping the oldest first. Defaults to 1000.
+	HistorySize int
+}
+
+// Delivery is a snapshot of a message and its delivery state.
+type Delivery struct {
+	messaging.Message
+	Headers     map[string]string
+	Attempts    int
+	LastError   string
+	AvailableAt time.Time
+}
+
+func (c *Config) maxDeliveryAttempts() int {
+	if c.MaxDeliveryAttempts <= 0 {
+		return 5
+	}
+
+	return c.MaxDeliveryAttempts
+}
+
+func (c *Config) retryDelay(attempt int) time.Duration {
+	if c.RetryBaseDelay <= 0 {
+		return 0
+	}
+
+	return messaging.RetryDelay(c.RetryBaseDelay, c.RetryMaxDelay, attempt)
+}
+
+func (c *Config) concurrency() int {
+	if c.Concurrency <= 0 {
+		return 1
+	}
+
+	return c.Concurrency
+}
+
+func (c *Config) historySize() int {
+	if c.HistorySize <= 0 {
+		return 1000
+	}
+
+	return c.HistorySize
+}
+
+func (c *Config) drainTimeout() time.Duration {
+	if c.DrainTimeout <= 0 {
+		return 30 * time.Second
+	}
+
+	return c.DrainTimeout
+}
+
+func New(logger observability.Logger, tracer observability.Tracer, cfg *Config) *Client {
+	return &Client{
+		inFlight: make(map[string]*Delivery),
+		notify:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		cfg:      cfg,
+		logger:   logger,
+		tracer:   tracer,
+	}
+}
+
+// Close rejects further publishes and releases subscribers waiting for
+// messages, which then drain their in-flight handlers and return.
+func (c *Client) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+
+	c.closed = true
+	close(c.done)
+}
+
+func (c *Client) Publish(ctx context.Context, msg proto.Message) (context.Context, error) {
+	ctx, complete := c.tracer.Span(ctx, "memory.Client.Publish")
+	defer complete()
+
+	m, err := messaging.NewMessage(msg, c.cfg.ServiceName)
+	if err != nil {
+		return ctx, err
+	}
+
+	headers := m.Headers()
+	c.tracer.Inject(ctx, headers)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ctx, fmt.Errorf("failed to publish message: %w", ErrClientClosed)
+	}
+
+	c.pending = append(c.pending, &Delivery{
+		Message:     m,
+		Headers:     headers,
+		AvailableAt: m.PublishedAt.Add(c.cfg.DeliveryDelay),
+	})
+
+	c.signal()
+
+	return ctx, nil
+}
+
+// Subscribe hands pending messages to the handler until ctx is cancelled or
+// the client is closed, then waits up to DrainTimeout for in-flight handlers
+// before returning.
+func (c *Client) Subscribe(ctx context.Context, handler messaging.Handler) error {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+
+	if closed {
+		return fmt.Errorf("failed to subscribe: %w", ErrClientClosed)
+	}
+
+	// handlers must be able to finish after ctx is cancelled, so they only
+	// inherit its values
+	handlerCtx := context.WithoutCancel(ctx)
+
+	var wg sync.WaitGroup
+	for range c.cfg.concurrency() {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				d, ok := c.next(ctx)
+				if !ok {
+					return
+				}
+
+				c.handleDelivery(handlerCtx, d, handler)
+			}
+		}()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-ctx.Done():
+	case <-c.done:
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(c.cfg.drainTimeout()):
+		c.logger.Warn("Timed out draining in-flight messages", "timeout", c.cfg.drainTimeout().String())
+	}
+
+	return nil
+}
+
+// next blocks until a message is available for delivery, ctx is done or the
+// client is closed.
+func (c *Client) next(ctx context.Context) (*Delivery, bool) {
+	for {
+		c.mu.Lock()
+
+		now := time.Now()
+		wait := time.Duration(-1)
+
+		for i, d := range c.pending {
+			if d.AvailableAt.After(now) {
+				if until := d.AvailableAt.Sub(now); wait < 0 || until < wait {
+					wait = until
+				}
+				continue
+			}
+
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			d.Attempts++
+			c.inFlight[d.ID] = d
+
+			// wake another worker for whatever is left
+			if len(c.pending) > 0 {
+				c.signal()
+			}
+
+			c.mu.Unlock()
+
+			return d, true
+		}
+
+		c.mu.Unlock()
+
+		var timer <-chan time.Time
+		if wait >= 0 {
+			timer = time.After(wait)
+		}
+
+		select {
+		case <-c.notify:
+		case <-timer:
+		case <-ctx.Done():
+			return nil, false
+		case <-c.done:
+			return nil, false
+		}
+	}
+}
+
+func (c *Client) handleDelivery(ctx context.Context, d *Delivery, handler messaging.Handler) {
+	ctx = c.tracer.Extract(ctx, d.Headers)
+	ctx, complete := c.tracer.Span(ctx, "memory.Client.Subscribe.Handler")
+	defer complete()
+
+	if err := handler(ctx, d.Message); err != nil {
+		c.logger.Error(fmt.Sprintf("failed to handle message: %v", err))
+		c.nack(d, err)
+		return
+	}
+
+	c.ack(d)
+}
+
+func (c *Client) ack(d *Delivery) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.inFlight, d.ID)
+	c.acked = appendHistory(c.acked, *d, c.cfg.historySize())
+}
+
+// nack requeues the message, or dead-letters it once the configured attempts
+// are exhausted.
+func (c *Client) nack(d *Delivery, handlerErr error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.inFlight, d.ID)
+	d.LastError = handlerErr.Error()
+
+	if d.Attempts >= c.cfg.maxDeliveryAttempts() {
+		c.logger.Warn("Delivery attempts exhausted, dead-lettering message", "attempts", d.Attempts, "message_id", d.ID)
+		c.dead = appendHistory(c.dead, *d, c.cfg.historySize())
+		return
+	}
+
+	d.AvailableAt = time.Now().Add(c.cfg.retryDelay(d.Attempts))
+	c.pending = append(c.pending, d)
+
+	c.signal()
+}
+
+// appendHistory appends d, dropping the oldest deliveries beyond size.
+func appendHistory(history []Delivery, d Delivery, size int) []Delivery {
+	history = append(history, d)
+
+	if over := len(history) - size; over > 0 {
+		history = append(history[:0], history[over:]...)
+	}
+
+	return history
+}
+
+func (c *Client) signal() {
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Pending returns the messages waiting to be delivered or redelivered.
+func (c *Client) Pending() []Delivery {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pending := make([]Delivery, 0, len(c.pending))
+	for _, d := range c.pending {
+		pending = append(pending, *d)
+	}
+
+	return pending
+}
+
+// InFlight returns the number of messages currently being handled.
+func (c *Client) InFlight() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.inFlight)
+}
+
+// Acked returns the most recent messages that were handled successfully, up
+// to HistorySize.
+func (c *Client) Acked() []Delivery {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]Delivery(nil), c.acked...)
+}
+
+// DeadLettered returns the most recent messages whose delivery attempts were
+// exhausted, up to HistorySize.
+func (c *Client) DeadLettered() []Delivery {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]Delivery(nil), c.dead...)
+}
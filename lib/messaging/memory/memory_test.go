@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/messaging"
+	"github.com/charmingruby/devicio/lib/proto/gen/pb"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Warn(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
+
+type nopTracer struct{}
+
+func (nopTracer) Span(ctx context.Context, _ string) (context.Context, func()) {
+	return ctx, func() {}
+}
+
+func (nopTracer) GetTraceIDFromContext(context.Context) string { return "" }
+
+func (nopTracer) Inject(context.Context, map[string]string) {}
+
+func (nopTracer) Extract(ctx context.Context, _ map[string]string) context.Context {
+	return ctx
+}
+
+func (nopTracer) Close() error { return nil }
+
+func TestCloseReleasesSubscribers(t *testing.T) {
+	client := New(nopLogger{}, nopTracer{}, &Config{Concurrency: 2})
+
+	returned := make(chan error, 1)
+	go func() {
+		returned <- client.Subscribe(context.Background(), func(context.Context, messaging.Message) error {
+			return nil
+		})
+	}()
+
+	client.Close()
+
+	select {
+	case err := <-returned:
+		if err != nil && !errors.Is(err, ErrClientClosed) {
+			t.Fatalf("Subscribe returned %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Subscribe did not return after Close")
+	}
+}
+
+func TestAckedHistoryIsBounded(t *testing.T) {
+	client := New(nopLogger{}, nopTracer{}, &Config{HistorySize: 3})
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for range 10 {
+		if _, err := client.Publish(ctx, &pb.DeviceRoutine{Id: "device-1"}); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+
+	published := client.Pending()
+
+	go client.Subscribe(ctx, func(context.Context, messaging.Message) error {
+		return nil
+	})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(client.Pending()) > 0 || client.InFlight() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("messages were not handled")
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	acked := client.Acked()
+	if len(acked) != 3 {
+		t.Fatalf("acked history holds %d deliveries, want 3", len(acked))
+	}
+
+	for i, d := range acked {
+		if want := published[len(published)-3+i].ID; d.ID != want {
+			t.Fatalf("acked[%d] = %q, want most recent delivery %q", i, d.ID, want)
+		}
+	}
+}
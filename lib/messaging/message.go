@@ -0,0 +1,103 @@
+package messaging
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/core/id"
+	"github.com/charmingruby/devicio/lib/proto/gen/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// SchemaVersion is the version of the lib/proto/domain contract stamped on
+// every published message. Bump it on incompatible changes.
+const SchemaVersion = "1"
+
+const (
+	HeaderContentType   = "content-type"
+	HeaderMessageID     = "x-message-id"
+	HeaderMessageType   = "x-message-type"
+	HeaderSchemaVersion = "x-schema-version"
+	HeaderProducer      = "x-producer"
+	HeaderPublishedAt   = "x-published-at"
+)
+
+// Message is a protobuf payload together with its envelope metadata.
+type Message struct {
+	ID            string
+	Type          string
+	SchemaVersion string
+	Producer      string
+	PublishedAt   time.Time
+	ContentType   string
+	Body          []byte
+}
+
+// NewMessage marshals msg and wraps it in an envelope identifying the
+// producer.
+func NewMessage(msg proto.Message, producer string) (Message, error) {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to marshal protobuf message: %w", err)
+	}
+
+	return Message{
+		ID:            id.New(),
+		Type:          string(proto.MessageName(msg)),
+		SchemaVersion: SchemaVersion,
+		Producer:      producer,
+		PublishedAt:   time.Now().UTC(),
+		ContentType:   ContentTypeProtobuf,
+		Body:          data,
+	}, nil
+}
+
+// Headers encodes the envelope metadata as transport headers.
+func (m Message) Headers() map[string]string {
+	return map[string]string{
+		HeaderContentType:   m.ContentType,
+		HeaderMessageID:     m.ID,
+		HeaderMessageType:   m.Type,
+		HeaderSchemaVersion: m.SchemaVersion,
+		HeaderProducer:      m.Producer,
+		HeaderPublishedAt:   m.PublishedAt.Format(time.RFC3339Nano),
+	}
+}
+
+// MessageFromHeaders rebuilds a message from transport headers. Missing
+// metadata is left empty so messages from older producers are still handled.
+func MessageFromHeaders(headers map[string]string, body []byte) Message {
+	msg := Message{
+		ID:            headers[HeaderMessageID],
+		Type:          headers[HeaderMessageType],
+		SchemaVersion: headers[HeaderSchemaVersion],
+		Producer:      headers[HeaderProducer],
+		ContentType:   headers[HeaderContentType],
+		Body:          body,
+	}
+
+	if publishedAt, err := time.Parse(time.RFC3339Nano, headers[HeaderPublishedAt]); err == nil {
+		msg.PublishedAt = publishedAt
+	}
+
+	return msg
+}
+
+// Unmarshal decodes the message body into out.
+func (m Message) Unmarshal(out proto.Message) error {
+	return proto.Unmarshal(m.Body, out)
+}
+
+// PartitionKey returns the ID of the device msg is about, so that backends
+// with partitions keep each device's messages in order. It is empty for
+// messages not tied to a device.
+func PartitionKey(msg proto.Message) string {
+	switch m := msg.(type) {
+	case *pb.DeviceRoutine:
+		return m.GetId()
+	case interface{ GetDeviceId() string }:
+		return m.GetDeviceId()
+	default:
+		return ""
+	}
+}
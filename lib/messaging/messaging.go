@@ -6,8 +6,14 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+const ContentTypeProtobuf = "application/protobuf"
+
+type Handler func(ctx context.Context, msg Message) error
+
 type Queue interface {
 	Publish(ctx context.Context, msg proto.Message) (context.Context, error)
-	Subscribe(ctx context.Context, handler func(context.Context, []byte) error) error
+	// Subscribe blocks dispatching messages to handler until ctx is cancelled,
+	// then drains in-flight handlers before returning.
+	Subscribe(ctx context.Context, handler Handler) error
 	Close()
 }
@@ -0,0 +1,466 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/messaging"
+	"github.com/charmingruby/devicio/lib/observability"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultFetchRetryBaseDelay = 500 * time.Millisecond
+	defaultFetchRetryMaxDelay  = 30 * time.Second
+)
+
+type Client struct {
+	conn *natsgo.Conn
+	// closed is closed once the connection has finished draining.
+	closed chan struct{}
+	js     jetstream.JetStream
+	cfg    *Config
+	logger observability.Logger
+	tracer observability.Tracer
+}
+
+type Config struct {
+	URL     string
+	Stream  string
+	Subject string
+	// ServiceName is stamped as the producer of published messages.
+	ServiceName string
+	// Durable is the consumer name shared by every subscriber. Defaults to
+	// "<Stream>-consumer".
+	Durable string
+	// MaxDeliveryAttempts bounds how many times a message is handled before
+	// it is dead-lettered. Defaults to 5.
+	MaxDeliveryAttempts int
+	// RetryBaseDelay is the redelivery delay after the first failure,
+	// doubled on each subsequent attempt. Defaults to 1s.
+	RetryBaseDelay time.Duration
+	// RetryMaxDelay caps the redelivery delay when set.
+	RetryMaxDelay time.Duration
+	// DeadLetterSubject defaults to "<Subject>.dead".
+	DeadLetterSubject string
+	// PrefetchCount limits messages buffered by the client. Defaults to
+	// Concurrency.
+	PrefetchCount int
+	// Concurrency is the number of handlers run in parallel. Defaults to 1.
+	Concurrency int
+	// DrainTimeout bounds how long Subscribe waits for in-flight handlers and
+	// Close waits for the connection to drain on shutdown. Defaults to 30s.
+	DrainTimeout time.Duration
+}
+
+func (c *Config) durable() string {
+	if c.Durable == "" {
+		return c.Stream + "-consumer"
+	}
+
+	return c.Durable
+}
+
+func (c *Config) maxDeliveryAttempts() int {
+	if c.MaxDeliveryAttempts <= 0 {
+		return 5
+	}
+
+	return c.MaxDeliveryAttempts
+}
+
+func (c *Config) retryDelay(attempt int) time.Duration {
+	base := c.RetryBaseDelay
+	if base <= 0 {
+		base = time.Second
+	}
+
+	return messaging.RetryDelay(base, c.RetryMaxDelay, attempt)
+}
+
+func (c *Config) deadLetterSubject() string {
+	if c.DeadLetterSubject == "" {
+		return c.Subject + ".dead"
+	}
+
+	return c.DeadLetterSubject
+}
+
+func (c *Config) concurrency() int {
+	if c.Concurrency <= 0 {
+		return 1
+	}
+
+	return c.Concurrency
+}
+
+func (c *Config) prefetchCount() int {
+	if c.PrefetchCount <= 0 {
+		return c.concurrency()
+	}
+
+	return c.PrefetchCount
+}
+
+func (c *Config) drainTimeout() time.Duration {
+	if c.DrainTimeout <= 0 {
+		return 30 * time.Second
+	}
+
+	return c.DrainTimeout
+}
+
+func New(logger observability.Logger, tracer observability.Tracer, cfg *Config) (*Client, error) {
+	closed := make(chan struct{})
+
+	conn, err := natsgo.Connect(cfg.URL,
+		natsgo.MaxReconnects(-1),
+		natsgo.DrainTimeout(cfg.drainTimeout()),
+		natsgo.ClosedHandler(func(*natsgo.Conn) {
+			close(closed)
+		}),
+		natsgo.DisconnectErrHandler(func(_ *natsgo.Conn, err error) {
+			logger.Warn("NATS connection lost", "error", err)
+		}),
+		natsgo.ReconnectHandler(func(conn *natsgo.Conn) {
+			logger.Info("NATS connection re-established", "url", conn.ConnectedUrl())
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     cfg.Stream,
+		Subjects: []string{cfg.Subject, cfg.deadLetterSubject()},
+		Storage:  jetstream.FileStorage,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to declare stream: %w", err)
+	}
+
+	return &Client{
+		conn:   conn,
+		closed: closed,
+		js:     js,
+		cfg:    cfg,
+		logger: logger,
+		tracer: tracer,
+	}, nil
+}
+
+// Close drains the connection, flushing the acks of in-flight messages before
+// closing it, and waits up to DrainTimeout for the drain to complete.
+func (c *Client) Close() {
+	if c.conn == nil {
+		return
+	}
+
+	if err := c.conn.Drain(); err != nil {
+		c.logger.Warn("Failed to drain NATS connection", "error", err)
+		c.conn.Close()
+		return
+	}
+
+	select {
+	case <-c.closed:
+	case <-time.After(c.cfg.drainTimeout()):
+		c.logger.Warn("Timed out draining NATS connection", "timeout", c.cfg.drainTimeout().String())
+		c.conn.Close()
+	}
+}
+
+func (c *Client) Publish(ctx context.Context, msg proto.Message) (context.Context, error) {
+	ctx, complete := c.tracer.Span(ctx, "nats.Client.Publish")
+	defer complete()
+
+	m, err := messaging.NewMessage(msg, c.cfg.ServiceName)
+	if err != nil {
+		return ctx, err
+	}
+
+	out := natsgo.NewMsg(c.cfg.Subject)
+	out.Data = m.Body
+	c.injectHeaders(ctx, m.Headers(), out.Header)
+
+	if _, err := c.js.PublishMsg(ctx, out, jetstream.WithMsgID(m.ID)); err != nil {
+		return ctx, fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	return ctx, nil
+}
+
+// Subscribe consumes the subject through a durable pull consumer until ctx is
+// cancelled, then drains in-flight handlers for up to DrainTimeout.
+func (c *Client) Subscribe(ctx context.Context, handler messaging.Handler) error {
+	consumer, err := c.js.CreateOrUpdateConsumer(ctx, c.cfg.Stream, jetstream.ConsumerConfig{
+		Durable:       c.cfg.durable(),
+		FilterSubject: c.cfg.Subject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		MaxDeliver:    c.cfg.maxDeliveryAttempts(),
+		MaxAckPending: c.cfg.prefetchCount(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	it, err := newIterator(consumer, c.cfg.prefetchCount())
+	if err != nil {
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	deliveries := make(chan jetstream.Msg)
+	stopped := make(chan struct{})
+
+	// fetchErr is written before deliveries is closed and read only after
+	// stopped is closed
+	var fetchErr error
+
+	go func() {
+		defer close(deliveries)
+
+		fetchErr = c.fetch(ctx, consumer, it, deliveries)
+	}()
+
+	go func() {
+		defer close(stopped)
+
+		// handlers must be able to finish after ctx is cancelled, so they
+		// only inherit its values
+		handlerCtx := context.WithoutCancel(ctx)
+
+		var wg sync.WaitGroup
+		for range c.cfg.concurrency() {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				for msg := range deliveries {
+					c.handleDelivery(handlerCtx, msg, handler)
+				}
+			}()
+		}
+
+		wg.Wait()
+	}()
+
+	select {
+	case <-stopped:
+		return fetchErr
+	case <-ctx.Done():
+	}
+
+	c.logger.Info("Stopping NATS consumer", "stream", c.cfg.Stream, "consumer", c.cfg.durable())
+
+	it.stop()
+
+	select {
+	case <-stopped:
+		c.logger.Info("NATS consumer drained", "stream", c.cfg.Stream, "consumer", c.cfg.durable())
+	case <-time.After(c.cfg.drainTimeout()):
+		c.logger.Warn("Timed out draining in-flight messages", "stream", c.cfg.Stream, "timeout", c.cfg.drainTimeout().String())
+	}
+
+	return nil
+}
+
+// fetch feeds deliveries from the consumer until the iterator is stopped.
+// Transient fetch errors are logged and the iterator is recreated with
+// backoff; only terminal errors, such as a deleted consumer or a closed
+// connection, are returned.
+func (c *Client) fetch(ctx context.Context, consumer jetstream.Consumer, it *iterator, deliveries chan<- jetstream.Msg) error {
+	for attempt := 0; ; {
+		msg, err := it.next()
+		if err == nil {
+			attempt = 0
+			deliveries <- msg
+			continue
+		}
+
+		if it.isStopped() {
+			return nil
+		}
+
+		if c.isTerminalFetchError(err) {
+			return fmt.Errorf("failed to fetch message: %w", err)
+		}
+
+		for {
+			attempt++
+			delay := messaging.RetryDelay(defaultFetchRetryBaseDelay, defaultFetchRetryMaxDelay, attempt)
+
+			c.logger.Warn("Failed to fetch NATS messages, retrying", "attempt", attempt, "delay", delay.String(), "error", err)
+
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil
+			}
+
+			err = it.renew(consumer, c.cfg.prefetchCount())
+			if err == nil || it.isStopped() {
+				break
+			}
+
+			if c.isTerminalFetchError(err) {
+				return fmt.Errorf("failed to fetch message: %w", err)
+			}
+		}
+	}
+}
+
+func (c *Client) isTerminalFetchError(err error) bool {
+	return c.conn.IsClosed() ||
+		errors.Is(err, natsgo.ErrConnectionClosed) ||
+		errors.Is(err, jetstream.ErrConsumerDeleted) ||
+		errors.Is(err, jetstream.ErrConsumerNotFound)
+}
+
+// iterator guards the message iterator of a subscription, which fetch
+// replaces after transient errors while Subscribe may stop it concurrently.
+type iterator struct {
+	mu      sync.Mutex
+	current jetstream.MessagesContext
+	stopped bool
+}
+
+func newIterator(consumer jetstream.Consumer, prefetchCount int) (*iterator, error) {
+	current, err := consumer.Messages(jetstream.PullMaxMessages(prefetchCount))
+	if err != nil {
+		return nil, err
+	}
+
+	return &iterator{current: current}, nil
+}
+
+func (it *iterator) next() (jetstream.Msg, error) {
+	it.mu.Lock()
+	current := it.current
+	it.mu.Unlock()
+
+	return current.Next()
+}
+
+// renew replaces the iterator with a fresh one, unless it has been stopped.
+func (it *iterator) renew(consumer jetstream.Consumer, prefetchCount int) error {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	if it.stopped {
+		return nil
+	}
+
+	current, err := consumer.Messages(jetstream.PullMaxMessages(prefetchCount))
+	if err != nil {
+		return err
+	}
+
+	it.current.Stop()
+	it.current = current
+
+	return nil
+}
+
+func (it *iterator) stop() {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	it.stopped = true
+	it.current.Stop()
+}
+
+func (it *iterator) isStopped() bool {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	return it.stopped
+}
+
+func (c *Client) handleDelivery(ctx context.Context, msg jetstream.Msg, handler messaging.Handler) {
+	headers := flattenHeaders(msg.Headers())
+
+	ctx = c.tracer.Extract(ctx, headers)
+	ctx, complete := c.tracer.Span(ctx, "nats.Client.Subscribe.Handler")
+	defer complete()
+
+	if err := handler(ctx, messaging.MessageFromHeaders(headers, msg.Data())); err != nil {
+		c.logger.Error(fmt.Sprintf("failed to handle message: %v", err))
+
+		if err := c.retry(ctx, msg, err); err != nil {
+			c.logger.Error(fmt.Sprintf("failed to retry message: %v", err))
+		}
+
+		return
+	}
+
+	if err := msg.Ack(); err != nil {
+		c.logger.Error(fmt.Sprintf("failed to ack message: %v", err))
+	}
+}
+
+// retry asks the server to redeliver the message after an exponential delay,
+// or copies it to the dead-letter subject and terminates it once the
+// configured attempts are exhausted.
+func (c *Client) retry(ctx context.Context, msg jetstream.Msg, handlerErr error) error {
+	md, err := msg.Metadata()
+	if err != nil {
+		return msg.Nak()
+	}
+
+	attempts := int(md.NumDelivered)
+	if attempts < c.cfg.maxDeliveryAttempts() {
+		return msg.NakWithDelay(c.cfg.retryDelay(attempts))
+	}
+
+	c.logger.Warn("Delivery attempts exhausted, dead-lettering message",
+		"attempts", attempts,
+		"subject", c.cfg.deadLetterSubject(),
+	)
+
+	dead := natsgo.NewMsg(c.cfg.deadLetterSubject())
+	dead.Data = msg.Data()
+	for k, v := range msg.Headers() {
+		dead.Header[k] = v
+	}
+	dead.Header.Set("X-Last-Error", handlerErr.Error())
+
+	if _, err := c.js.PublishMsg(ctx, dead); err != nil {
+		return fmt.Errorf("failed to dead-letter message: %w", err)
+	}
+
+	return msg.Term()
+}
+
+func (c *Client) injectHeaders(ctx context.Context, headers map[string]string, out natsgo.Header) {
+	c.tracer.Inject(ctx, headers)
+
+	for k, v := range headers {
+		out.Set(k, v)
+	}
+}
+
+func flattenHeaders(header natsgo.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+
+	for k := range header {
+		headers[k] = header.Get(k)
+	}
+
+	return headers
+}
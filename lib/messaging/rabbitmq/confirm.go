@@ -0,0 +1,160 @@
+package rabbitmq
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/streadway/amqp"
+)
+
+var (
+	ErrPublishNacked = errors.New("message nacked by the broker")
+	ErrChannelClosed = errors.New("channel closed before the message was confirmed")
+)
+
+// ReturnedError reports a mandatory message the broker could not route to
+// any queue.
+type ReturnedError struct {
+	Exchange   string
+	RoutingKey string
+	ReplyCode  uint16
+	ReplyText  string
+}
+
+func (e *ReturnedError) Error() string {
+	return fmt.Sprintf("message returned by the broker: exchange=%q routing_key=%q code=%d reason=%s",
+		e.Exchange, e.RoutingKey, e.ReplyCode, e.ReplyText)
+}
+
+type pendingConfirm struct {
+	messageID string
+	result    chan error
+}
+
+// confirmer tracks publisher confirms for a single channel. Delivery tags are
+// assigned by the broker sequentially per channel, so publishing and tag
+// bookkeeping happen under the same lock.
+type confirmer struct {
+	mu       sync.Mutex
+	nextTag  uint64
+	pending  map[uint64]pendingConfirm
+	returned map[string]amqp.Return
+}
+
+func newConfirmer(ch Channel) (*confirmer, error) {
+	if err := ch.Confirm(false); err != nil {
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
+	cf := &confirmer{
+		pending:  make(map[uint64]pendingConfirm),
+		returned: make(map[string]amqp.Return),
+	}
+
+	confirms := ch.NotifyPublish(make(chan amqp.Confirmation, 256))
+	returns := ch.NotifyReturn(make(chan amqp.Return, 64))
+
+	go cf.dispatch(confirms, returns)
+
+	return cf, nil
+}
+
+// publish sends the message and returns a channel that receives the broker
+// outcome once it is known.
+func (cf *confirmer) publish(ch Channel, exchange, key string, msg amqp.Publishing) (<-chan error, error) {
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+
+	if err := ch.Publish(exchange, key, true, false, msg); err != nil {
+		return nil, err
+	}
+
+	cf.nextTag++
+
+	result := make(chan error, 1)
+	cf.pending[cf.nextTag] = pendingConfirm{messageID: msg.MessageId, result: result}
+
+	return result, nil
+}
+
+func (cf *confirmer) dispatch(confirms <-chan amqp.Confirmation, returns <-chan amqp.Return) {
+	for {
+		select {
+		case ret, ok := <-returns:
+			if !ok {
+				returns = nil
+				continue
+			}
+
+			cf.mu.Lock()
+			cf.returned[ret.MessageId] = ret
+			cf.mu.Unlock()
+		case conf, ok := <-confirms:
+			if !ok {
+				cf.failPending()
+				return
+			}
+
+			// the broker sends basic.return before the matching ack, so any
+			// return for this message is already buffered
+			cf.drainReturns(returns)
+			cf.resolve(conf)
+		}
+	}
+}
+
+func (cf *confirmer) drainReturns(returns <-chan amqp.Return) {
+	for {
+		select {
+		case ret, ok := <-returns:
+			if !ok {
+				return
+			}
+
+			cf.mu.Lock()
+			cf.returned[ret.MessageId] = ret
+			cf.mu.Unlock()
+		default:
+			return
+		}
+	}
+}
+
+func (cf *confirmer) resolve(conf amqp.Confirmation) {
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+
+	p, ok := cf.pending[conf.DeliveryTag]
+	if !ok {
+		return
+	}
+	delete(cf.pending, conf.DeliveryTag)
+
+	ret, returned := cf.returned[p.messageID]
+	delete(cf.returned, p.messageID)
+
+	switch {
+	case !conf.Ack:
+		p.result <- ErrPublishNacked
+	case returned:
+		p.result <- &ReturnedError{
+			Exchange:   ret.Exchange,
+			RoutingKey: ret.RoutingKey,
+			ReplyCode:  ret.ReplyCode,
+			ReplyText:  ret.ReplyText,
+		}
+	default:
+		p.result <- nil
+	}
+}
+
+func (cf *confirmer) failPending() {
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+
+	for tag, p := range cf.pending {
+		p.result <- ErrChannelClosed
+		delete(cf.pending, tag)
+	}
+}
@@ -0,0 +1,53 @@
+package rabbitmq
+
+import "github.com/streadway/amqp"
+
+// Dialer opens a broker connection. It is injectable so the client can be
+// exercised against fake brokers.
+type Dialer func(url string) (Connection, error)
+
+type Connection interface {
+	Channel() (Channel, error)
+	NotifyClose(receiver chan *amqp.Error) chan *amqp.Error
+	Close() error
+}
+
+type Channel interface {
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
+	Qos(prefetchCount, prefetchSize int, global bool) error
+	Cancel(consumer string, noWait bool) error
+	Confirm(noWait bool) error
+	NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation
+	NotifyReturn(returns chan amqp.Return) chan amqp.Return
+	NotifyClose(receiver chan *amqp.Error) chan *amqp.Error
+	Close() error
+}
+
+func AMQPDialer(url string) (Connection, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return &amqpConnection{conn: conn}, nil
+}
+
+type amqpConnection struct {
+	conn *amqp.Connection
+}
+
+func (c *amqpConnection) Channel() (Channel, error) {
+	return c.conn.Channel()
+}
+
+func (c *amqpConnection) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	return c.conn.NotifyClose(receiver)
+}
+
+func (c *amqpConnection) Close() error {
+	return c.conn.Close()
+}
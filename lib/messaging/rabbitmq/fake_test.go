@@ -0,0 +1,431 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+var errFakeBroker = errors.New("fake broker failure")
+
+// fakeBroker is an in-process stand-in for RabbitMQ reached through the
+// Dialer, Connection and Channel interfaces. Exchanges are fanout only and
+// retry queue TTLs are not enforced; tests expire retry queues explicitly.
+type fakeBroker struct {
+	mu          sync.Mutex
+	queues      map[string]*fakeQueue
+	bindings    map[string][]string
+	conns       []*fakeConnection
+	dials       int
+	failDials   int
+	failConsume int
+	acks        int
+	nacks       int
+	deliveryTag uint64
+}
+
+type fakeQueue struct {
+	args     amqp.Table
+	pending  []amqp.Delivery
+	consumer chan amqp.Delivery
+	channel  *fakeChannel
+	tag      string
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{
+		queues:   make(map[string]*fakeQueue),
+		bindings: make(map[string][]string),
+	}
+}
+
+func (b *fakeBroker) dial(string) (Connection, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.dials++
+
+	if b.failDials > 0 {
+		b.failDials--
+		return nil, errFakeBroker
+	}
+
+	conn := &fakeConnection{broker: b}
+	b.conns = append(b.conns, conn)
+
+	return conn, nil
+}
+
+// drop closes the most recent connection as if the broker went away.
+func (b *fakeBroker) drop() {
+	b.mu.Lock()
+	conn := b.conns[len(b.conns)-1]
+	b.mu.Unlock()
+
+	conn.shutdown(&amqp.Error{Code: amqp.ConnectionForced, Reason: "connection dropped"})
+}
+
+func (b *fakeBroker) connections() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.conns)
+}
+
+func (b *fakeBroker) dialAttempts() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.dials
+}
+
+func (b *fakeBroker) settled() (acks, nacks int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.acks, b.nacks
+}
+
+// messages returns the deliveries waiting in a queue without a consumer.
+func (b *fakeBroker) messages(queue string) []amqp.Delivery {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q, ok := b.queues[queue]
+	if !ok {
+		return nil
+	}
+
+	return append([]amqp.Delivery(nil), q.pending...)
+}
+
+// expire dead-letters the pending messages of a retry queue the way the
+// broker does once their TTL elapses.
+func (b *fakeBroker) expire(queue string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q := b.queues[queue]
+	target, _ := q.args["x-dead-letter-routing-key"].(string)
+
+	pending := q.pending
+	q.pending = nil
+
+	for _, d := range pending {
+		b.enqueue(target, d)
+	}
+}
+
+func (b *fakeBroker) publish(exchange, key string, msg amqp.Publishing) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.route(exchange, key, msg)
+}
+
+func (b *fakeBroker) route(exchange, key string, msg amqp.Publishing) {
+	targets := []string{key}
+	if exchange != "" {
+		targets = b.bindings[exchange]
+	}
+
+	for _, name := range targets {
+		b.deliveryTag++
+
+		b.enqueue(name, amqp.Delivery{
+			Headers:     msg.Headers,
+			ContentType: msg.ContentType,
+			MessageId:   msg.MessageId,
+			Type:        msg.Type,
+			AppId:       msg.AppId,
+			Timestamp:   msg.Timestamp,
+			Body:        msg.Body,
+			DeliveryTag: b.deliveryTag,
+			Exchange:    exchange,
+			RoutingKey:  key,
+		})
+	}
+}
+
+func (b *fakeBroker) enqueue(name string, d amqp.Delivery) {
+	q, ok := b.queues[name]
+	if !ok {
+		return
+	}
+
+	if q.consumer == nil {
+		q.pending = append(q.pending, d)
+		return
+	}
+
+	d.Acknowledger = q.channel
+	q.consumer <- d
+}
+
+type fakeConnection struct {
+	broker *fakeBroker
+	closed bool
+	notify []chan *amqp.Error
+}
+
+func (c *fakeConnection) Channel() (Channel, error) {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+
+	if c.closed {
+		return nil, amqp.ErrClosed
+	}
+
+	return &fakeChannel{conn: c}, nil
+}
+
+func (c *fakeConnection) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+
+	c.notify = append(c.notify, receiver)
+
+	return receiver
+}
+
+func (c *fakeConnection) Close() error {
+	c.shutdown(nil)
+	return nil
+}
+
+// shutdown stops every consumer of the connection and notifies listeners,
+// sending reason first when the close was not requested by the client.
+func (c *fakeConnection) shutdown(reason *amqp.Error) {
+	b := c.broker
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+
+	c.closed = true
+
+	for _, q := range b.queues {
+		if q.channel != nil && q.channel.conn == c {
+			close(q.consumer)
+			q.consumer, q.channel = nil, nil
+		}
+	}
+
+	for _, receiver := range c.notify {
+		if reason != nil {
+			receiver <- reason
+		}
+		close(receiver)
+	}
+}
+
+type fakeChannel struct {
+	conn *fakeConnection
+}
+
+func (ch *fakeChannel) ExchangeDeclare(string, string, bool, bool, bool, bool, amqp.Table) error {
+	return ch.err()
+}
+
+func (ch *fakeChannel) QueueDeclare(name string, _, _, _, _ bool, args amqp.Table) (amqp.Queue, error) {
+	b := ch.conn.broker
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ch.conn.closed {
+		return amqp.Queue{}, amqp.ErrClosed
+	}
+
+	if _, ok := b.queues[name]; !ok {
+		b.queues[name] = &fakeQueue{args: args}
+	}
+
+	return amqp.Queue{Name: name}, nil
+}
+
+func (ch *fakeChannel) QueueBind(name, _, exchange string, _ bool, _ amqp.Table) error {
+	b := ch.conn.broker
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, bound := range b.bindings[exchange] {
+		if bound == name {
+			return nil
+		}
+	}
+
+	b.bindings[exchange] = append(b.bindings[exchange], name)
+
+	return nil
+}
+
+func (ch *fakeChannel) Publish(exchange, key string, _, _ bool, msg amqp.Publishing) error {
+	b := ch.conn.broker
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ch.conn.closed {
+		return amqp.ErrClosed
+	}
+
+	b.route(exchange, key, msg)
+
+	return nil
+}
+
+func (ch *fakeChannel) Consume(queue, consumer string, _, _, _, _ bool, _ amqp.Table) (<-chan amqp.Delivery, error) {
+	b := ch.conn.broker
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ch.conn.closed {
+		return nil, amqp.ErrClosed
+	}
+
+	if b.failConsume > 0 {
+		b.failConsume--
+		return nil, errFakeBroker
+	}
+
+	q, ok := b.queues[queue]
+	if !ok {
+		return nil, errFakeBroker
+	}
+
+	q.consumer = make(chan amqp.Delivery, 64)
+	q.channel = ch
+	q.tag = consumer
+
+	for _, d := range q.pending {
+		d.Acknowledger = ch
+		q.consumer <- d
+	}
+	q.pending = nil
+
+	return q.consumer, nil
+}
+
+func (ch *fakeChannel) Qos(int, int, bool) error {
+	return ch.err()
+}
+
+func (ch *fakeChannel) Cancel(consumer string, _ bool) error {
+	b := ch.conn.broker
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, q := range b.queues {
+		if q.channel == ch && q.tag == consumer {
+			close(q.consumer)
+			q.consumer, q.channel = nil, nil
+		}
+	}
+
+	return nil
+}
+
+func (ch *fakeChannel) Confirm(bool) error {
+	return ch.err()
+}
+
+func (ch *fakeChannel) NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation {
+	return confirm
+}
+
+func (ch *fakeChannel) NotifyReturn(returns chan amqp.Return) chan amqp.Return {
+	return returns
+}
+
+func (ch *fakeChannel) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	return ch.conn.NotifyClose(receiver)
+}
+
+func (ch *fakeChannel) Close() error {
+	return nil
+}
+
+func (ch *fakeChannel) Ack(uint64, bool) error {
+	b := ch.conn.broker
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.acks++
+
+	return nil
+}
+
+func (ch *fakeChannel) Nack(uint64, bool, bool) error {
+	b := ch.conn.broker
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nacks++
+
+	return nil
+}
+
+func (ch *fakeChannel) Reject(tag uint64, requeue bool) error {
+	return ch.Nack(tag, false, requeue)
+}
+
+func (ch *fakeChannel) err() error {
+	ch.conn.broker.mu.Lock()
+	defer ch.conn.broker.mu.Unlock()
+
+	if ch.conn.closed {
+		return amqp.ErrClosed
+	}
+
+	return nil
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Warn(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
+
+type nopTracer struct{}
+
+func (nopTracer) Span(ctx context.Context, _ string) (context.Context, func()) {
+	return ctx, func() {}
+}
+
+func (nopTracer) GetTraceIDFromContext(context.Context) string { return "" }
+
+func (nopTracer) Inject(context.Context, map[string]string) {}
+
+func (nopTracer) Extract(ctx context.Context, _ map[string]string) context.Context {
+	return ctx
+}
+
+func (nopTracer) Close() error { return nil }
+
+// eventually polls cond until it holds or the timeout elapses.
+func eventually(t *testing.T, cond func() bool, msg string) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting: %s", msg)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
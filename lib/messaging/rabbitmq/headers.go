@@ -0,0 +1,59 @@
+package rabbitmq
+
+import (
+	"context"
+
+	"github.com/charmingruby/devicio/lib/messaging"
+	"github.com/streadway/amqp"
+)
+
+func (c *Client) injectHeaders(ctx context.Context, headers map[string]string) amqp.Table {
+	c.tracer.Inject(ctx, headers)
+
+	table := make(amqp.Table, len(headers))
+	for k, v := range headers {
+		table[k] = v
+	}
+
+	return table
+}
+
+func (c *Client) extractHeaders(ctx context.Context, headers amqp.Table) context.Context {
+	return c.tracer.Extract(ctx, stringHeaders(headers))
+}
+
+func stringHeaders(headers amqp.Table) map[string]string {
+	carrier := make(map[string]string, len(headers))
+
+	for k, v := range headers {
+		if s, ok := v.(string); ok {
+			carrier[k] = s
+		}
+	}
+
+	return carrier
+}
+
+// messageFromDelivery rebuilds the envelope from the delivery headers,
+// falling back to the native AMQP properties.
+func messageFromDelivery(d amqp.Delivery) messaging.Message {
+	msg := messaging.MessageFromHeaders(stringHeaders(d.Headers), d.Body)
+
+	if msg.ID == "" {
+		msg.ID = d.MessageId
+	}
+	if msg.Type == "" {
+		msg.Type = d.Type
+	}
+	if msg.Producer == "" {
+		msg.Producer = d.AppId
+	}
+	if msg.ContentType == "" {
+		msg.ContentType = d.ContentType
+	}
+	if msg.PublishedAt.IsZero() {
+		msg.PublishedAt = d.Timestamp
+	}
+
+	return msg
+}
@@ -0,0 +1,76 @@
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/charmingruby/devicio/lib/messaging"
+	"github.com/charmingruby/devicio/lib/observability/trace"
+	"github.com/charmingruby/devicio/lib/proto/gen/pb"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceContextRoundTrip(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := trace.NewOtelTracerWithProvider("rabbitmq-test", sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(recorder),
+	))
+
+	broker := newFakeBroker()
+
+	client, err := NewWithDialer(nopLogger{}, tracer, &Config{QueueName: "routines"}, broker.dial)
+	if err != nil {
+		t.Fatalf("NewWithDialer: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan oteltrace.SpanContext, 1)
+	go client.Subscribe(ctx, func(ctx context.Context, _ messaging.Message) error {
+		received <- oteltrace.SpanContextFromContext(ctx)
+		return nil
+	})
+
+	producerCtx, complete := tracer.Span(context.Background(), "producer")
+	if _, err := client.Publish(producerCtx, &pb.DeviceRoutine{Id: "device-1"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	complete()
+
+	consumer := <-received
+
+	producer := oteltrace.SpanContextFromContext(producerCtx)
+	if consumer.TraceID() != producer.TraceID() {
+		t.Fatalf("consumer trace ID = %s, want %s", consumer.TraceID(), producer.TraceID())
+	}
+
+	var publish, handler sdktrace.ReadOnlySpan
+	for _, s := range recorder.Started() {
+		switch s.Name() {
+		case "rabbitmq.Client.Publish":
+			publish = s
+		case "rabbitmq.Client.Subscribe.Handler":
+			handler = s
+		}
+	}
+
+	if publish == nil || handler == nil {
+		t.Fatalf("expected publish and handler spans to be recorded")
+	}
+
+	if got, want := handler.Parent().SpanID(), publish.SpanContext().SpanID(); got != want {
+		t.Fatalf("handler parent span = %s, want publish span %s", got, want)
+	}
+
+	if !handler.Parent().IsRemote() {
+		t.Fatalf("handler parent span should be extracted from the message headers")
+	}
+
+	if handler.SpanContext().SpanID() != consumer.SpanID() {
+		t.Fatalf("handler context does not carry the handler span")
+	}
+}
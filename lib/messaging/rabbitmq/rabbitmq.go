@@ -2,28 +2,190 @@ package rabbitmq
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
+	"github.com/charmingruby/devicio/lib/core/id"
+	"github.com/charmingruby/devicio/lib/messaging"
 	"github.com/charmingruby/devicio/lib/observability"
 	"github.com/streadway/amqp"
 	"google.golang.org/protobuf/proto"
 )
 
+var ErrClientClosed = errors.New("client closed")
+
 type Client struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
-	cfg     *Config
-	logger  observability.Logger
-	tracer  observability.Tracer
+	mu          sync.RWMutex
+	session     *session
+	reconnected chan struct{}
+	subscribed  bool
+	// consumerTags and subscriptions track active Subscribe calls so that
+	// Close can stop deliveries and wait for in-flight handlers.
+	consumerTags  []string
+	subscriptions sync.WaitGroup
+	done          chan struct{}
+	closeOnce     sync.Once
+	dial          Dialer
+	cfg           *Config
+	logger        observability.Logger
+	tracer        observability.Tracer
 }
 
 type Config struct {
 	URL       string
 	QueueName string
+	// Exchange, when set, is declared and used as the publish target instead
+	// of the default exchange. QueueName may then be empty for publish-only
+	// clients; otherwise the queue is bound to the exchange.
+	Exchange string
+	// ExchangeKind defaults to "fanout".
+	ExchangeKind string
+	// RoutingKey is used when publishing and binding. Defaults to QueueName.
+	RoutingKey string
+	// Exclusive declares the queue and its retry queues exclusive to the
+	// connection, so they are deleted once it closes. Use it for taps bound
+	// to an exchange that must not hold messages while nobody consumes.
+	Exclusive bool
+	// ServiceName is stamped as the producer of published messages.
+	ServiceName string
+	// MaxDeliveryAttempts bounds how many times a message is handled before
+	// it is dead-lettered. Defaults to 5.
+	MaxDeliveryAttempts int
+	// RetryBaseDelay is the delay before the first retry, doubled on each
+	// subsequent attempt. Defaults to 1s.
+	RetryBaseDelay time.Duration
+	// RetryMaxDelay caps the retry delay when set.
+	RetryMaxDelay time.Duration
+	// DeadLetterExchange defaults to "<QueueName>.dlx".
+	DeadLetterExchange string
+	// DeadLetterQueue defaults to "<QueueName>.dead".
+	DeadLetterQueue string
+	// ReconnectBaseDelay is the initial backoff between reconnection
+	// attempts, doubled on each failure. Defaults to 500ms.
+	ReconnectBaseDelay time.Duration
+	// ReconnectMaxDelay caps the reconnection backoff. Defaults to 30s.
+	ReconnectMaxDelay time.Duration
+	// PublisherConfirms puts the channel in confirm mode: Publish blocks
+	// until the broker acks, nacks or returns the message.
+	PublisherConfirms bool
+	// PublishTimeout bounds how long Publish waits for a confirm when set.
+	PublishTimeout time.Duration
+	// PrefetchCount limits unacknowledged deliveries per consumer. Defaults
+	// to Concurrency.
+	PrefetchCount int
+	// Concurrency is the number of handlers run in parallel per
+	// subscription. Defaults to 1.
+	Concurrency int
+	// DrainTimeout bounds how long Subscribe and Close wait for in-flight
+	// handlers on shutdown. Defaults to 30s.
+	DrainTimeout time.Duration
+}
+
+func (c *Config) exchangeKind() string {
+	if c.ExchangeKind == "" {
+		return amqp.ExchangeFanout
+	}
+
+	return c.ExchangeKind
+}
+
+func (c *Config) routingKey() string {
+	if c.RoutingKey == "" {
+		return c.QueueName
+	}
+
+	return c.RoutingKey
+}
+
+func (c *Config) prefetchCount() int {
+	if c.PrefetchCount <= 0 {
+		return c.concurrency()
+	}
+
+	return c.PrefetchCount
+}
+
+func (c *Config) drainTimeout() time.Duration {
+	if c.DrainTimeout <= 0 {
+		return 30 * time.Second
+	}
+
+	return c.DrainTimeout
+}
+
+func (c *Config) concurrency() int {
+	if c.Concurrency <= 0 {
+		return 1
+	}
+
+	return c.Concurrency
+}
+
+// session groups the resources tied to a single broker connection.
+type session struct {
+	conn     Connection
+	channel  Channel
+	confirms *confirmer
 }
 
 func New(logger observability.Logger, tracer observability.Tracer, cfg *Config) (*Client, error) {
-	conn, err := amqp.Dial(cfg.URL)
+	return NewWithDialer(logger, tracer, cfg, AMQPDialer)
+}
+
+func NewWithDialer(logger observability.Logger, tracer observability.Tracer, cfg *Config, dial Dialer) (*Client, error) {
+	c := &Client{
+		reconnected: make(chan struct{}),
+		done:        make(chan struct{}),
+		dial:        dial,
+		logger:      logger,
+		tracer:      tracer,
+		cfg:         cfg,
+	}
+
+	s, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	c.session = s
+
+	c.watch(s)
+
+	return c, nil
+}
+
+// declareTopology declares the configured exchange and queue, binding them
+// together when both are set.
+func declareTopology(ch Channel, cfg *Config) error {
+	if cfg.Exchange != "" {
+		if err := ch.ExchangeDeclare(cfg.Exchange, cfg.exchangeKind(), true, false, false, false, nil); err != nil {
+			return fmt.Errorf("failed to declare exchange: %w", err)
+		}
+
+		if cfg.QueueName == "" {
+			return nil
+		}
+	}
+
+	if _, err := ch.QueueDeclare(cfg.QueueName, !cfg.Exclusive, false, cfg.Exclusive, false, nil); err != nil {
+		return fmt.Errorf("failed to declare queue: %w", err)
+	}
+
+	if cfg.Exchange != "" {
+		if err := ch.QueueBind(cfg.QueueName, cfg.routingKey(), cfg.Exchange, false, nil); err != nil {
+			return fmt.Errorf("failed to bind queue: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// connect dials the broker, opens a channel and declares the topology the
+// client relies on.
+func (c *Client) connect() (*session, error) {
+	conn, err := c.dial(c.cfg.URL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
 	}
@@ -34,42 +196,97 @@ func New(logger observability.Logger, tracer observability.Tracer, cfg *Config)
 		return nil, fmt.Errorf("failed to create channel: %w", err)
 	}
 
-	_, err = ch.QueueDeclare(cfg.QueueName, true, false, false, false, nil)
-	if err != nil {
+	if err := declareTopology(ch, c.cfg); err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("failed to declare queue: %w", err)
+		return nil, err
 	}
 
-	return &Client{
-		conn:    conn,
-		channel: ch,
-		logger:  logger,
-		tracer:  tracer,
-		cfg:     cfg,
-	}, nil
-}
+	c.mu.RLock()
+	subscribed := c.subscribed
+	c.mu.RUnlock()
 
-func (c *Client) Close() {
-	if c.channel != nil {
-		c.channel.Close()
+	if subscribed {
+		if err := declareRetryTopology(ch, c.cfg); err != nil {
+			conn.Close()
+			return nil, err
+		}
 	}
-	if c.conn != nil {
-		c.conn.Close()
+
+	s := &session{conn: conn, channel: ch}
+
+	if c.cfg.PublisherConfirms {
+		s.confirms, err = newConfirmer(ch)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
 	}
+
+	return s, nil
+}
+
+func (c *Client) currentSession() *session {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.session
+}
+
+// Close stops consuming, waits for in-flight handlers to settle their
+// deliveries and then closes the channel and connection.
+func (c *Client) Close() {
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		close(c.done)
+		c.mu.Unlock()
+
+		c.mu.RLock()
+		for _, tag := range c.consumerTags {
+			if err := c.session.channel.Cancel(tag, false); err != nil {
+				c.logger.Warn("Failed to cancel RabbitMQ consumer", "consumer", tag, "error", err)
+			}
+		}
+		c.mu.RUnlock()
+
+		drained := make(chan struct{})
+		go func() {
+			c.subscriptions.Wait()
+			close(drained)
+		}()
+
+		select {
+		case <-drained:
+		case <-time.After(c.cfg.drainTimeout()):
+			c.logger.Warn("Timed out draining in-flight messages", "timeout", c.cfg.drainTimeout().String())
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.session != nil {
+			c.session.channel.Close()
+			c.session.conn.Close()
+		}
+	})
 }
 
 func (c *Client) Publish(ctx context.Context, msg proto.Message) (context.Context, error) {
 	ctx, complete := c.tracer.Span(ctx, "rabbitmq.Client.Publish")
 	defer complete()
 
-	data, err := proto.Marshal(msg)
+	m, err := messaging.NewMessage(msg, c.cfg.ServiceName)
 	if err != nil {
-		return ctx, fmt.Errorf("failed to marshal protobuf message: %w", err)
+		return ctx, err
 	}
 
-	err = c.channel.Publish("", c.cfg.QueueName, false, false, amqp.Publishing{
-		ContentType: "application/protobuf",
-		Body:        data,
+	err = c.publish(ctx, c.cfg.Exchange, c.cfg.routingKey(), amqp.Publishing{
+		ContentType: m.ContentType,
+		MessageId:   m.ID,
+		Type:        m.Type,
+		AppId:       m.Producer,
+		Timestamp:   m.PublishedAt,
+		Headers:     c.injectHeaders(ctx, m.Headers()),
+		Body:        m.Body,
 	})
 	if err != nil {
 		return ctx, fmt.Errorf("failed to publish message: %w", err)
@@ -78,42 +295,232 @@ func (c *Client) Publish(ctx context.Context, msg proto.Message) (context.Contex
 	return ctx, nil
 }
 
-func (c *Client) Subscribe(ctx context.Context, handler func(context.Context, []byte) error) error {
-	msgs, err := c.channel.Consume(
-		c.cfg.QueueName,
-		"",    // consumer
+// publish sends the message on the current channel. In confirm mode it waits
+// for the broker outcome, the publish timeout or the context, whichever comes
+// first.
+func (c *Client) publish(ctx context.Context, exchange, key string, msg amqp.Publishing) error {
+	s := c.currentSession()
+
+	if s.confirms == nil {
+		return s.channel.Publish(exchange, key, false, false, msg)
+	}
+
+	if msg.MessageId == "" {
+		msg.MessageId = id.New()
+	}
+
+	if c.cfg.PublishTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.PublishTimeout)
+		defer cancel()
+	}
+
+	result, err := s.confirms.publish(s.channel, exchange, key, msg)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for publisher confirm: %w", ctx.Err())
+	}
+}
+
+// Subscribe consumes the queue until ctx is cancelled or the client is
+// closed. On cancellation it stops the consumer, waits up to DrainTimeout for
+// in-flight handlers to settle their deliveries and then returns.
+func (c *Client) Subscribe(ctx context.Context, handler messaging.Handler) error {
+	consumerTag := id.New()
+
+	c.mu.Lock()
+	select {
+	case <-c.done:
+		c.mu.Unlock()
+		return ErrClientClosed
+	default:
+	}
+
+	c.subscribed = true
+	c.consumerTags = append(c.consumerTags, consumerTag)
+	c.subscriptions.Add(1)
+	ch, reconnected := c.session.channel, c.reconnected
+	c.mu.Unlock()
+
+	defer c.removeConsumerTag(consumerTag)
+
+	if err := declareRetryTopology(ch, c.cfg); err != nil {
+		c.subscriptions.Done()
+		return err
+	}
+
+	msgs, err := consume(ch, c.cfg, consumerTag)
+	if err != nil {
+		c.subscriptions.Done()
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	stopped := make(chan struct{})
+
+	go func() {
+		defer c.subscriptions.Done()
+		defer close(stopped)
+
+		// handlers must be able to finish after ctx is cancelled, so they
+		// only inherit its values
+		handlerCtx := context.WithoutCancel(ctx)
+
+		for {
+			c.handle(handlerCtx, msgs, handler)
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			// the delivery channel closed: resume consuming once the
+			// connection has been re-established
+			var ok bool
+			msgs, reconnected, ok = c.resume(ctx, consumerTag, reconnected)
+			if !ok {
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+	}
+
+	c.logger.Info("Stopping RabbitMQ consumer", "queue", c.cfg.QueueName, "consumer", consumerTag)
+
+	if err := c.currentSession().channel.Cancel(consumerTag, false); err != nil {
+		c.logger.Warn("Failed to cancel RabbitMQ consumer", "consumer", consumerTag, "error", err)
+	}
+
+	select {
+	case <-stopped:
+		c.logger.Info("RabbitMQ consumer drained", "queue", c.cfg.QueueName, "consumer", consumerTag)
+	case <-time.After(c.cfg.drainTimeout()):
+		c.logger.Warn("Timed out draining in-flight messages", "queue", c.cfg.QueueName, "timeout", c.cfg.drainTimeout().String())
+	}
+
+	return nil
+}
+
+// resume waits for the connection to be re-established and consumes again.
+// Consuming can still fail on the new connection, so it is retried with the
+// reconnect backoff rather than waiting for another connection loss. It
+// reports false once ctx is cancelled or the client is closed.
+func (c *Client) resume(ctx context.Context, consumerTag string, reconnected chan struct{}) (<-chan amqp.Delivery, chan struct{}, bool) {
+	select {
+	case <-reconnected:
+	case <-ctx.Done():
+		return nil, nil, false
+	case <-c.done:
+		return nil, nil, false
+	}
+
+	for attempt := 0; ; attempt++ {
+		c.mu.RLock()
+		ch, next := c.session.channel, c.reconnected
+		c.mu.RUnlock()
+
+		msgs, err := consume(ch, c.cfg, consumerTag)
+		if err == nil {
+			c.logger.Info("RabbitMQ subscription resumed", "queue", c.cfg.QueueName)
+			return msgs, next, true
+		}
+
+		delay := c.cfg.reconnectDelay(attempt)
+
+		c.logger.Warn("Failed to resume RabbitMQ subscription", "attempt", attempt+1, "delay", delay.String(), "error", err)
+
+		select {
+		case <-time.After(delay):
+		case <-next:
+		case <-ctx.Done():
+			return nil, nil, false
+		case <-c.done:
+			return nil, nil, false
+		}
+	}
+}
+
+func (c *Client) removeConsumerTag(consumerTag string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, tag := range c.consumerTags {
+		if tag == consumerTag {
+			c.consumerTags = append(c.consumerTags[:i], c.consumerTags[i+1:]...)
+			return
+		}
+	}
+}
+
+func consume(ch Channel, cfg *Config, consumerTag string) (<-chan amqp.Delivery, error) {
+	if err := ch.Qos(cfg.prefetchCount(), 0, false); err != nil {
+		return nil, fmt.Errorf("failed to set prefetch count: %w", err)
+	}
+
+	return ch.Consume(
+		cfg.QueueName,
+		consumerTag,
 		false, // autoAck
 		false, // exclusive
 		false, // noLocal
 		false, // noWait
 		nil,   // args
 	)
-	if err != nil {
-		return fmt.Errorf("failed to subscribe: %w", err)
+}
+
+// handle fans deliveries out to a bounded pool of workers and returns once the
+// delivery channel is closed and every in-flight message has been settled.
+func (c *Client) handle(ctx context.Context, msgs <-chan amqp.Delivery, handler messaging.Handler) {
+	if msgs == nil {
+		return
 	}
 
-	go func() {
-		for msg := range msgs {
-			ctx, complete := c.tracer.Span(ctx, "rabbitmq.Client.Subscribe.Handler")
+	var wg sync.WaitGroup
 
-			if err := handler(ctx, msg.Body); err != nil {
-				c.logger.Error(fmt.Sprintf("failed to handle message: %v", err))
+	for range c.cfg.concurrency() {
+		wg.Add(1)
 
-				if err := msg.Nack(false, true); err != nil {
-					c.logger.Error(fmt.Sprintf("failed to nack message: %v", err))
-				}
+		go func() {
+			defer wg.Done()
 
-				complete()
-				continue
+			for msg := range msgs {
+				c.handleDelivery(ctx, msg, handler)
 			}
+		}()
+	}
 
-			if err := msg.Ack(false); err != nil {
-				c.logger.Error(fmt.Sprintf("failed to ack message: %v", err))
-			}
+	wg.Wait()
+}
 
-			complete()
+func (c *Client) handleDelivery(ctx context.Context, msg amqp.Delivery, handler messaging.Handler) {
+	ctx = c.extractHeaders(ctx, msg.Headers)
+	ctx, complete := c.tracer.Span(ctx, "rabbitmq.Client.Subscribe.Handler")
+	defer complete()
+
+	if err := handler(ctx, messageFromDelivery(msg)); err != nil {
+		c.logger.Error(fmt.Sprintf("failed to handle message: %v", err))
+
+		if err := c.retry(ctx, msg, err); err != nil {
+			c.logger.Error(fmt.Sprintf("failed to retry message: %v", err))
+
+			if err := msg.Nack(false, true); err != nil {
+				c.logger.Error(fmt.Sprintf("failed to nack message: %v", err))
+			}
 		}
-	}()
 
-	return nil
+		return
+	}
+
+	if err := msg.Ack(false); err != nil {
+		c.logger.Error(fmt.Sprintf("failed to ack message: %v", err))
+	}
 }
@@ -0,0 +1,103 @@
+package rabbitmq
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+const (
+	defaultReconnectBaseDelay = 500 * time.Millisecond
+	defaultReconnectMaxDelay  = 30 * time.Second
+)
+
+func (c *Config) reconnectDelay(attempt int) time.Duration {
+	base, max := c.ReconnectBaseDelay, c.ReconnectMaxDelay
+	if base <= 0 {
+		base = defaultReconnectBaseDelay
+	}
+	if max <= 0 {
+		max = defaultReconnectMaxDelay
+	}
+
+	delay := base << attempt
+	if delay > max || delay <= 0 {
+		delay = max
+	}
+
+	// jitter between half and the full delay so that clients do not reconnect in lockstep
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// watch registers for close notifications on the given connection and
+// channel, reconnecting once either closes unless the client itself is being
+// closed.
+func (c *Client) watch(s *session) {
+	connClosed := s.conn.NotifyClose(make(chan *amqp.Error, 1))
+	chClosed := s.channel.NotifyClose(make(chan *amqp.Error, 1))
+
+	go c.awaitClose(s.conn, connClosed, chClosed)
+}
+
+func (c *Client) awaitClose(conn Connection, connClosed, chClosed chan *amqp.Error) {
+	var reason *amqp.Error
+	select {
+	case reason = <-connClosed:
+	case reason = <-chClosed:
+	case <-c.done:
+		return
+	}
+
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+
+	c.logger.Warn("RabbitMQ connection lost", "error", reason)
+
+	conn.Close()
+
+	c.reconnect()
+}
+
+func (c *Client) reconnect() {
+	for attempt := 0; ; attempt++ {
+		delay := c.cfg.reconnectDelay(attempt)
+
+		c.logger.Info("Reconnecting to RabbitMQ", "attempt", attempt+1, "delay", delay.String())
+
+		select {
+		case <-time.After(delay):
+		case <-c.done:
+			return
+		}
+
+		s, err := c.connect()
+		if err != nil {
+			c.logger.Warn("Failed to reconnect to RabbitMQ", "attempt", attempt+1, "error", err)
+			continue
+		}
+
+		c.mu.Lock()
+		select {
+		case <-c.done:
+			c.mu.Unlock()
+			s.conn.Close()
+			return
+		default:
+		}
+
+		c.session = s
+		close(c.reconnected)
+		c.reconnected = make(chan struct{})
+		c.mu.Unlock()
+
+		c.logger.Info("RabbitMQ connection re-established", "attempts", attempt+1)
+
+		c.watch(s)
+
+		return
+	}
+}
@@ -0,0 +1,116 @@
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/messaging"
+	"github.com/streadway/amqp"
+)
+
+func newReconnectingClient(t *testing.T, broker *fakeBroker) *Client {
+	t.Helper()
+
+	client, err := NewWithDialer(nopLogger{}, nopTracer{}, &Config{
+		QueueName:          "routines",
+		ReconnectBaseDelay: time.Millisecond,
+		ReconnectMaxDelay:  5 * time.Millisecond,
+	}, broker.dial)
+	if err != nil {
+		t.Fatalf("NewWithDialer: %v", err)
+	}
+	t.Cleanup(client.Close)
+
+	return client
+}
+
+func subscribeIDs(t *testing.T, client *Client) <-chan string {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	ids := make(chan string, 16)
+	go client.Subscribe(ctx, func(_ context.Context, msg messaging.Message) error {
+		ids <- msg.ID
+		return nil
+	})
+
+	return ids
+}
+
+func expectDelivery(t *testing.T, ids <-chan string, want string) {
+	t.Helper()
+
+	select {
+	case got := <-ids:
+		if got != want {
+			t.Fatalf("delivered message %q, want %q", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("message %q was not delivered", want)
+	}
+}
+
+func TestSubscribeResumesAfterConnectionLoss(t *testing.T) {
+	broker := newFakeBroker()
+	client := newReconnectingClient(t, broker)
+	ids := subscribeIDs(t, client)
+
+	broker.publish("", "routines", amqp.Publishing{MessageId: "before"})
+	expectDelivery(t, ids, "before")
+
+	broker.drop()
+
+	eventually(t, func() bool { return broker.connections() == 2 }, "client to reconnect")
+
+	broker.publish("", "routines", amqp.Publishing{MessageId: "after"})
+	expectDelivery(t, ids, "after")
+}
+
+func TestReconnectRetriesFailedDials(t *testing.T) {
+	broker := newFakeBroker()
+	client := newReconnectingClient(t, broker)
+	ids := subscribeIDs(t, client)
+
+	broker.publish("", "routines", amqp.Publishing{MessageId: "before"})
+	expectDelivery(t, ids, "before")
+
+	broker.mu.Lock()
+	broker.failDials = 3
+	broker.mu.Unlock()
+
+	broker.drop()
+
+	broker.publish("", "routines", amqp.Publishing{MessageId: "after"})
+	expectDelivery(t, ids, "after")
+
+	if got := broker.dialAttempts(); got != 5 {
+		t.Fatalf("dial attempts = %d, want 5", got)
+	}
+}
+
+func TestSubscribeRetriesConsumeAfterReconnect(t *testing.T) {
+	broker := newFakeBroker()
+	client := newReconnectingClient(t, broker)
+	ids := subscribeIDs(t, client)
+
+	broker.publish("", "routines", amqp.Publishing{MessageId: "before"})
+	expectDelivery(t, ids, "before")
+
+	// the connection comes back but the first consume attempts on it fail,
+	// and nothing else will drop the connection again
+	broker.mu.Lock()
+	broker.failConsume = 2
+	broker.mu.Unlock()
+
+	broker.drop()
+
+	broker.publish("", "routines", amqp.Publishing{MessageId: "after"})
+	expectDelivery(t, ids, "after")
+
+	if got := broker.connections(); got != 2 {
+		t.Fatalf("connections = %d, want 2", got)
+	}
+}
@@ -0,0 +1,141 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/messaging"
+	"github.com/streadway/amqp"
+)
+
+const (
+	attemptsHeader  = "x-delivery-attempts"
+	lastErrorHeader = "x-last-error"
+
+	defaultMaxDeliveryAttempts = 5
+	defaultRetryBaseDelay      = time.Second
+)
+
+func (c *Config) maxDeliveryAttempts() int {
+	if c.MaxDeliveryAttempts <= 0 {
+		return defaultMaxDeliveryAttempts
+	}
+
+	return c.MaxDeliveryAttempts
+}
+
+func (c *Config) retryBaseDelay() time.Duration {
+	if c.RetryBaseDelay <= 0 {
+		return defaultRetryBaseDelay
+	}
+
+	return c.RetryBaseDelay
+}
+
+func (c *Config) deadLetterExchange() string {
+	if c.DeadLetterExchange == "" {
+		return c.QueueName + ".dlx"
+	}
+
+	return c.DeadLetterExchange
+}
+
+func (c *Config) deadLetterQueue() string {
+	if c.DeadLetterQueue == "" {
+		return c.QueueName + ".dead"
+	}
+
+	return c.DeadLetterQueue
+}
+
+func (c *Config) retryQueue(attempt int) string {
+	return fmt.Sprintf("%s.retry.%d", c.QueueName, attempt)
+}
+
+func (c *Config) retryDelay(attempt int) time.Duration {
+	return messaging.RetryDelay(c.retryBaseDelay(), c.RetryMaxDelay, attempt)
+}
+
+// declareRetryTopology declares the dead-letter exchange and queue, and one
+// delayed retry queue per attempt. Retry queues hold messages for their TTL
+// and then dead-letter them back into the main queue.
+func declareRetryTopology(ch Channel, cfg *Config) error {
+	if err := ch.ExchangeDeclare(cfg.deadLetterExchange(), amqp.ExchangeFanout, !cfg.Exclusive, cfg.Exclusive, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare dead-letter exchange: %w", err)
+	}
+
+	if _, err := ch.QueueDeclare(cfg.deadLetterQueue(), !cfg.Exclusive, false, cfg.Exclusive, false, nil); err != nil {
+		return fmt.Errorf("failed to declare dead-letter queue: %w", err)
+	}
+
+	if err := ch.QueueBind(cfg.deadLetterQueue(), "", cfg.deadLetterExchange(), false, nil); err != nil {
+		return fmt.Errorf("failed to bind dead-letter queue: %w", err)
+	}
+
+	for attempt := 1; attempt < cfg.maxDeliveryAttempts(); attempt++ {
+		_, err := ch.QueueDeclare(cfg.retryQueue(attempt), !cfg.Exclusive, false, cfg.Exclusive, false, amqp.Table{
+			"x-message-ttl":             int32(cfg.retryDelay(attempt).Milliseconds()),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": cfg.QueueName,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to declare retry queue: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func deliveryAttempts(msg amqp.Delivery) int {
+	switch v := msg.Headers[attemptsHeader].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 0
+	}
+}
+
+// retry moves a failed delivery to the next delayed retry queue, or to the
+// dead-letter exchange once the configured attempts are exhausted. The
+// original delivery is acked only after the copy has been published.
+func (c *Client) retry(ctx context.Context, msg amqp.Delivery, handlerErr error) error {
+	attempts := deliveryAttempts(msg) + 1
+
+	headers := amqp.Table{}
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	headers[attemptsHeader] = int32(attempts)
+	headers[lastErrorHeader] = handlerErr.Error()
+
+	exchange, routingKey := "", c.cfg.retryQueue(attempts)
+	if attempts >= c.cfg.maxDeliveryAttempts() {
+		exchange, routingKey = c.cfg.deadLetterExchange(), ""
+
+		c.logger.Warn("Delivery attempts exhausted, dead-lettering message",
+			"attempts", attempts,
+			"exchange", exchange,
+		)
+	}
+
+	err := c.publish(ctx, exchange, routingKey, amqp.Publishing{
+		ContentType:  msg.ContentType,
+		MessageId:    msg.MessageId,
+		Type:         msg.Type,
+		AppId:        msg.AppId,
+		Timestamp:    msg.Timestamp,
+		Headers:      headers,
+		DeliveryMode: amqp.Persistent,
+		Body:         msg.Body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to republish message: %w", err)
+	}
+
+	return msg.Ack(false)
+}
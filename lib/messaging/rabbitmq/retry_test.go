@@ -0,0 +1,74 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/messaging"
+	"github.com/charmingruby/devicio/lib/proto/gen/pb"
+)
+
+func TestFailedDeliveryIsRetriedThenDeadLettered(t *testing.T) {
+	broker := newFakeBroker()
+
+	cfg := &Config{
+		QueueName:           "routines",
+		MaxDeliveryAttempts: 2,
+		RetryBaseDelay:      time.Millisecond,
+	}
+
+	client, err := NewWithDialer(nopLogger{}, nopTracer{}, cfg, broker.dial)
+	if err != nil {
+		t.Fatalf("NewWithDialer: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handlerErr := errors.New("handler failed")
+	go client.Subscribe(ctx, func(context.Context, messaging.Message) error {
+		return handlerErr
+	})
+
+	if _, err := client.Publish(context.Background(), &pb.DeviceRoutine{Id: "device-1"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	eventually(t, func() bool {
+		return len(broker.messages(cfg.retryQueue(1))) == 1
+	}, "message to be republished to the first retry queue")
+
+	retried := broker.messages(cfg.retryQueue(1))[0]
+	if got := deliveryAttempts(retried); got != 1 {
+		t.Fatalf("retried delivery attempts = %d, want 1", got)
+	}
+
+	// the retry queue's TTL elapses and the message is routed back to the
+	// main queue, where it fails for the last time
+	broker.expire(cfg.retryQueue(1))
+
+	eventually(t, func() bool {
+		return len(broker.messages(cfg.deadLetterQueue())) == 1
+	}, "message to be dead-lettered")
+
+	dead := broker.messages(cfg.deadLetterQueue())[0]
+	if got := deliveryAttempts(dead); got != 2 {
+		t.Fatalf("dead-lettered delivery attempts = %d, want 2", got)
+	}
+
+	if got := dead.Headers[lastErrorHeader]; got != handlerErr.Error() {
+		t.Fatalf("dead-lettered last error = %v, want %q", got, handlerErr.Error())
+	}
+
+	if dead.MessageId != retried.MessageId {
+		t.Fatalf("dead-lettered message ID = %q, want %q", dead.MessageId, retried.MessageId)
+	}
+
+	acks, nacks := broker.settled()
+	if acks != 2 || nacks != 0 {
+		t.Fatalf("settled deliveries = %d acks, %d nacks, want 2 acks and no nacks", acks, nacks)
+	}
+}
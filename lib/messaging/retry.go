@@ -0,0 +1,13 @@
+package messaging
+
+import (
+	"time"
+
+	"github.com/charmingruby/devicio/lib/resilience"
+)
+
+// RetryDelay returns the exponential backoff for the given 1-based attempt,
+// starting at base and capped at max when max is set.
+func RetryDelay(base, max time.Duration, attempt int) time.Duration {
+	return resilience.Backoff(base, max, attempt)
+}
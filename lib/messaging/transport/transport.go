@@ -0,0 +1,82 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/charmingruby/devicio/lib/messaging"
+	"github.com/charmingruby/devicio/lib/messaging/kafka"
+	"github.com/charmingruby/devicio/lib/messaging/memory"
+	"github.com/charmingruby/devicio/lib/messaging/nats"
+	"github.com/charmingruby/devicio/lib/messaging/rabbitmq"
+	"github.com/charmingruby/devicio/lib/observability"
+)
+
+const (
+	BackendRabbitMQ = "rabbitmq"
+	BackendNATS     = "nats"
+	BackendKafka    = "kafka"
+	// BackendMemory keeps messages inside the process, so it only carries
+	// traffic between publishers and subscribers of the same process.
+	BackendMemory = "memory"
+)
+
+var ErrUnknownBackend = errors.New("unknown messaging backend")
+
+// Config selects a messaging backend and carries the settings for each of
+// them; only the selected one is used.
+type Config struct {
+	Backend string
+	// ServiceName is stamped as the producer of published messages on
+	// whichever backend is selected.
+	ServiceName string
+
+	RabbitMQ rabbitmq.Config
+	NATS     nats.Config
+	Kafka    kafka.Config
+	Memory   memory.Config
+}
+
+// New builds the messaging.Queue for the configured backend, defaulting to
+// RabbitMQ.
+func New(logger observability.Logger, tracer observability.Tracer, cfg *Config) (messaging.Queue, error) {
+	switch cfg.Backend {
+	case "", BackendRabbitMQ:
+		cfg.RabbitMQ.ServiceName = cfg.ServiceName
+
+		client, err := rabbitmq.New(logger, tracer, &cfg.RabbitMQ)
+		if err != nil {
+			return nil, err
+		}
+
+		return client, nil
+	case BackendNATS:
+		cfg.NATS.ServiceName = cfg.ServiceName
+
+		client, err := nats.New(logger, tracer, &cfg.NATS)
+		if err != nil {
+			return nil, err
+		}
+
+		return client, nil
+	case BackendKafka:
+		cfg.Kafka.ServiceName = cfg.ServiceName
+
+		client, err := kafka.New(logger, tracer, &cfg.Kafka)
+		if err != nil {
+			return nil, err
+		}
+
+		return client, nil
+	case BackendMemory:
+		cfg.Memory.ServiceName = cfg.ServiceName
+
+		logger.Warn("In-memory messaging backend selected: messages are not shared with other processes",
+			"service", cfg.ServiceName,
+		)
+
+		return memory.New(logger, tracer, &cfg.Memory), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBackend, cfg.Backend)
+	}
+}
@@ -6,6 +6,7 @@ const (
 	HistogramMetricType   = "histogram"
 	CounterMetricType     = "counter"
 	CounterListMetricType = "counter_list"
+	GaugeMetricType       = "gauge"
 )
 
 var (
@@ -31,10 +32,17 @@ type CounterListInput struct {
 	LabelNames []string
 }
 
+type GaugeInput struct {
+	Name      string
+	Help      string
+	Namespace string
+}
+
 type Meter interface {
 	NewHistogram(input HistogramInput)
 	NewCounter(input CounterInput)
 	NewCounterList(input CounterListInput)
+	NewGauge(input GaugeInput)
 	GetMetric(name, metricType string) (any, error)
 }
 
@@ -43,6 +51,7 @@ func ValidateMetricType(metricType string) bool {
 		HistogramMetricType:   true,
 		CounterMetricType:     true,
 		CounterListMetricType: true,
+		GaugeMetricType:       true,
 	}
 
 	return validTypes[metricType]
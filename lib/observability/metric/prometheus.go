@@ -52,6 +52,18 @@ func (p *PrometheusMeter) NewCounterList(input observability.CounterListInput) {
 	prometheus.MustRegister(counterList)
 }
 
+func (p *PrometheusMeter) NewGauge(input observability.GaugeInput) {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:      input.Name,
+		Help:      input.Help,
+		Namespace: input.Namespace,
+	})
+
+	p.metrics[input.Name] = gauge
+
+	prometheus.MustRegister(gauge)
+}
+
 func (p *PrometheusMeter) GetMetric(name, metricType string) (any, error) {
 	if !observability.ValidateMetricType(metricType) {
 		return nil, observability.ErrInvalidMetricType
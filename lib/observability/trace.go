@@ -5,5 +5,7 @@ import "context"
 type Tracer interface {
 	Span(ctx context.Context, name string) (context.Context, func())
 	GetTraceIDFromContext(ctx context.Context) string
+	Inject(ctx context.Context, carrier map[string]string)
+	Extract(ctx context.Context, carrier map[string]string) context.Context
 	Close() error
 }
@@ -5,6 +5,7 @@ import (
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
@@ -12,8 +13,9 @@ import (
 )
 
 type OtelTracer struct {
-	tracer  trace.Tracer
-	cleanup func() error
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	cleanup    func() error
 }
 
 func NewOtelTracer(serviceName string) (*OtelTracer, error) {
@@ -32,16 +34,27 @@ func NewOtelTracer(serviceName string) (*OtelTracer, error) {
 		sdktrace.WithResource(r),
 	)
 
+	return NewOtelTracerWithProvider(serviceName, traceProvider), nil
+}
+
+// NewOtelTracerWithProvider builds a tracer on top of an existing provider,
+// which allows wiring custom span processors such as in-memory recorders.
+func NewOtelTracerWithProvider(serviceName string, traceProvider *sdktrace.TracerProvider) *OtelTracer {
+	propagator := propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	)
+
 	otel.SetTracerProvider(traceProvider)
+	otel.SetTextMapPropagator(propagator)
 
-	t := &OtelTracer{
-		tracer: otel.Tracer(serviceName),
+	return &OtelTracer{
+		tracer:     traceProvider.Tracer(serviceName),
+		propagator: propagator,
 		cleanup: func() error {
 			return traceProvider.Shutdown(context.Background())
 		},
 	}
-
-	return t, nil
 }
 
 func (t *OtelTracer) Span(ctx context.Context, name string) (context.Context, func()) {
@@ -63,6 +76,16 @@ func (t *OtelTracer) GetTraceIDFromContext(ctx context.Context) string {
 	return span.SpanContext().TraceID().String()
 }
 
+// Inject writes the W3C trace context and baggage of ctx into the carrier.
+func (t *OtelTracer) Inject(ctx context.Context, carrier map[string]string) {
+	t.propagator.Inject(ctx, propagation.MapCarrier(carrier))
+}
+
+// Extract returns a copy of ctx carrying the remote span context found in the carrier.
+func (t *OtelTracer) Extract(ctx context.Context, carrier map[string]string) context.Context {
+	return t.propagator.Extract(ctx, propagation.MapCarrier(carrier))
+}
+
 func (t *OtelTracer) Close() error {
 	return t.cleanup()
 }
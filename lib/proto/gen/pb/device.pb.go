@@ -161,35 +161,367 @@ func (x *DeviceRoutine) GetDispatchedAt() *timestamppb.Timestamp {
 	return nil
 }
 
+type DeviceStatusChanged struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	DeviceId       string                 `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	PreviousStatus DeviceStatus           `protobuf:"varint,2,opt,name=previous_status,json=previousStatus,proto3,enum=domain.DeviceStatus" json:"previous_status,omitempty"`
+	CurrentStatus  DeviceStatus           `protobuf:"varint,3,opt,name=current_status,json=currentStatus,proto3,enum=domain.DeviceStatus" json:"current_status,omitempty"`
+	Area           string                 `protobuf:"bytes,4,opt,name=area,proto3" json:"area,omitempty"`
+	RoutineId      string                 `protobuf:"bytes,5,opt,name=routine_id,json=routineId,proto3" json:"routine_id,omitempty"`
+	ChangedAt      *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=changed_at,json=changedAt,proto3" json:"changed_at,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *DeviceStatusChanged) Reset() {
+	*x = DeviceStatusChanged{}
+	mi := &file_device_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeviceStatusChanged) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeviceStatusChanged) ProtoMessage() {}
+
+func (x *DeviceStatusChanged) ProtoReflect() protoreflect.Message {
+	mi := &file_device_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeviceStatusChanged.ProtoReflect.Descriptor instead.
+func (*DeviceStatusChanged) Descriptor() ([]byte, []int) {
+	return file_device_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *DeviceStatusChanged) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *DeviceStatusChanged) GetPreviousStatus() DeviceStatus {
+	if x != nil {
+		return x.PreviousStatus
+	}
+	return DeviceStatus_UNSPECIFIED
+}
+
+func (x *DeviceStatusChanged) GetCurrentStatus() DeviceStatus {
+	if x != nil {
+		return x.CurrentStatus
+	}
+	return DeviceStatus_UNSPECIFIED
+}
+
+func (x *DeviceStatusChanged) GetArea() string {
+	if x != nil {
+		return x.Area
+	}
+	return ""
+}
+
+func (x *DeviceStatusChanged) GetRoutineId() string {
+	if x != nil {
+		return x.RoutineId
+	}
+	return ""
+}
+
+func (x *DeviceStatusChanged) GetChangedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ChangedAt
+	}
+	return nil
+}
+
+type DeviceAlert struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Rule          string                 `protobuf:"bytes,2,opt,name=rule,proto3" json:"rule,omitempty"`
+	Severity      string                 `protobuf:"bytes,3,opt,name=severity,proto3" json:"severity,omitempty"`
+	State         string                 `protobuf:"bytes,4,opt,name=state,proto3" json:"state,omitempty"`
+	DeviceId      string                 `protobuf:"bytes,5,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	Area          string                 `protobuf:"bytes,6,opt,name=area,proto3" json:"area,omitempty"`
+	Message       string                 `protobuf:"bytes,7,opt,name=message,proto3" json:"message,omitempty"`
+	FiredAt       *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=fired_at,json=firedAt,proto3" json:"fired_at,omitempty"`
+	ResolvedAt    *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=resolved_at,json=resolvedAt,proto3" json:"resolved_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeviceAlert) Reset() {
+	*x = DeviceAlert{}
+	mi := &file_device_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeviceAlert) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeviceAlert) ProtoMessage() {}
+
+func (x *DeviceAlert) ProtoReflect() protoreflect.Message {
+	mi := &file_device_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeviceAlert.ProtoReflect.Descriptor instead.
+func (*DeviceAlert) Descriptor() ([]byte, []int) {
+	return file_device_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *DeviceAlert) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *DeviceAlert) GetRule() string {
+	if x != nil {
+		return x.Rule
+	}
+	return ""
+}
+
+func (x *DeviceAlert) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *DeviceAlert) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *DeviceAlert) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *DeviceAlert) GetArea() string {
+	if x != nil {
+		return x.Area
+	}
+	return ""
+}
+
+func (x *DeviceAlert) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *DeviceAlert) GetFiredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FiredAt
+	}
+	return nil
+}
+
+func (x *DeviceAlert) GetResolvedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ResolvedAt
+	}
+	return nil
+}
+
+type DeviceHeartbeatMissed struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DeviceId      string                 `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	Area          string                 `protobuf:"bytes,2,opt,name=area,proto3" json:"area,omitempty"`
+	LastSeenAt    *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=last_seen_at,json=lastSeenAt,proto3" json:"last_seen_at,omitempty"`
+	DetectedAt    *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=detected_at,json=detectedAt,proto3" json:"detected_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeviceHeartbeatMissed) Reset() {
+	*x = DeviceHeartbeatMissed{}
+	mi := &file_device_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeviceHeartbeatMissed) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeviceHeartbeatMissed) ProtoMessage() {}
+
+func (x *DeviceHeartbeatMissed) ProtoReflect() protoreflect.Message {
+	mi := &file_device_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeviceHeartbeatMissed.ProtoReflect.Descriptor instead.
+func (*DeviceHeartbeatMissed) Descriptor() ([]byte, []int) {
+	return file_device_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *DeviceHeartbeatMissed) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *DeviceHeartbeatMissed) GetArea() string {
+	if x != nil {
+		return x.Area
+	}
+	return ""
+}
+
+func (x *DeviceHeartbeatMissed) GetLastSeenAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastSeenAt
+	}
+	return nil
+}
+
+func (x *DeviceHeartbeatMissed) GetDetectedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DetectedAt
+	}
+	return nil
+}
+
+type CapturedRoutine struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CapturedAt    *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=captured_at,json=capturedAt,proto3" json:"captured_at,omitempty"`
+	Routine       *DeviceRoutine         `protobuf:"bytes,2,opt,name=routine,proto3" json:"routine,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CapturedRoutine) Reset() {
+	*x = CapturedRoutine{}
+	mi := &file_device_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CapturedRoutine) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapturedRoutine) ProtoMessage() {}
+
+func (x *CapturedRoutine) ProtoReflect() protoreflect.Message {
+	mi := &file_device_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapturedRoutine.ProtoReflect.Descriptor instead.
+func (*CapturedRoutine) Descriptor() ([]byte, []int) {
+	return file_device_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CapturedRoutine) GetCapturedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CapturedAt
+	}
+	return nil
+}
+
+func (x *CapturedRoutine) GetRoutine() *DeviceRoutine {
+	if x != nil {
+		return x.Routine
+	}
+	return nil
+}
+
 var File_device_proto protoreflect.FileDescriptor
 
-var file_device_proto_rawDesc = string([]byte{
-	0x0a, 0x0c, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06,
-	0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x1a, 0x1f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d,
-	0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xde, 0x01, 0x0a, 0x0d, 0x44, 0x65, 0x76, 0x69,
-	0x63, 0x65, 0x52, 0x6f, 0x75, 0x74, 0x69, 0x6e, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x2c, 0x0a, 0x06, 0x73, 0x74, 0x61,
-	0x74, 0x75, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x14, 0x2e, 0x64, 0x6f, 0x6d, 0x61,
-	0x69, 0x6e, 0x2e, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52,
-	0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65,
-	0x78, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x78,
-	0x74, 0x12, 0x20, 0x0a, 0x0b, 0x64, 0x69, 0x61, 0x67, 0x6e, 0x6f, 0x73, 0x74, 0x69, 0x63, 0x73,
-	0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x64, 0x69, 0x61, 0x67, 0x6e, 0x6f, 0x73, 0x74,
-	0x69, 0x63, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x61, 0x72, 0x65, 0x61, 0x18, 0x05, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x04, 0x61, 0x72, 0x65, 0x61, 0x12, 0x3f, 0x0a, 0x0d, 0x64, 0x69, 0x73, 0x70, 0x61,
-	0x74, 0x63, 0x68, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a,
-	0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
-	0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x0c, 0x64, 0x69, 0x73, 0x70,
-	0x61, 0x74, 0x63, 0x68, 0x65, 0x64, 0x41, 0x74, 0x2a, 0x52, 0x0a, 0x0c, 0x44, 0x65, 0x76, 0x69,
-	0x63, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x0f, 0x0a, 0x0b, 0x55, 0x4e, 0x53, 0x50,
-	0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07, 0x48, 0x45, 0x41,
-	0x4c, 0x54, 0x48, 0x59, 0x10, 0x01, 0x12, 0x0b, 0x0a, 0x07, 0x57, 0x41, 0x52, 0x4e, 0x49, 0x4e,
-	0x47, 0x10, 0x02, 0x12, 0x09, 0x0a, 0x05, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x10, 0x03, 0x12, 0x0c,
-	0x0a, 0x08, 0x43, 0x52, 0x49, 0x54, 0x49, 0x43, 0x41, 0x4c, 0x10, 0x04, 0x42, 0x12, 0x5a, 0x10,
-	0x6c, 0x69, 0x62, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x67, 0x65, 0x6e, 0x2f, 0x70, 0x62,
-	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
-})
+const file_device_proto_rawDesc = "" +
+	"\n" +
+	"\fdevice.proto\x12\x06domain\x1a\x1fgoogle/protobuf/timestamp.proto\"\xde\x01\n" +
+	"\rDeviceRoutine\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12,\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x14.domain.DeviceStatusR\x06status\x12\x18\n" +
+	"\acontext\x18\x03 \x01(\tR\acontext\x12 \n" +
+	"\vdiagnostics\x18\x04 \x01(\tR\vdiagnostics\x12\x12\n" +
+	"\x04area\x18\x05 \x01(\tR\x04area\x12?\n" +
+	"\rdispatched_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\fdispatchedAt\"\x9c\x02\n" +
+	"\x13DeviceStatusChanged\x12\x1b\n" +
+	"\tdevice_id\x18\x01 \x01(\tR\bdeviceId\x12=\n" +
+	"\x0fprevious_status\x18\x02 \x01(\x0e2\x14.domain.DeviceStatusR\x0epreviousStatus\x12;\n" +
+	"\x0ecurrent_status\x18\x03 \x01(\x0e2\x14.domain.DeviceStatusR\rcurrentStatus\x12\x12\n" +
+	"\x04area\x18\x04 \x01(\tR\x04area\x12\x1d\n" +
+	"\n" +
+	"routine_id\x18\x05 \x01(\tR\troutineId\x129\n" +
+	"\n" +
+	"changed_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tchangedAt\"\xa2\x02\n" +
+	"\vDeviceAlert\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04rule\x18\x02 \x01(\tR\x04rule\x12\x1a\n" +
+	"\bseverity\x18\x03 \x01(\tR\bseverity\x12\x14\n" +
+	"\x05state\x18\x04 \x01(\tR\x05state\x12\x1b\n" +
+	"\tdevice_id\x18\x05 \x01(\tR\bdeviceId\x12\x12\n" +
+	"\x04area\x18\x06 \x01(\tR\x04area\x12\x18\n" +
+	"\amessage\x18\a \x01(\tR\amessage\x125\n" +
+	"\bfired_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\afiredAt\x12;\n" +
+	"\vresolved_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"resolvedAt\"\xc3\x01\n" +
+	"\x15DeviceHeartbeatMissed\x12\x1b\n" +
+	"\tdevice_id\x18\x01 \x01(\tR\bdeviceId\x12\x12\n" +
+	"\x04area\x18\x02 \x01(\tR\x04area\x12<\n" +
+	"\flast_seen_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"lastSeenAt\x12;\n" +
+	"\vdetected_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"detectedAt\"\x7f\n" +
+	"\x0fCapturedRoutine\x12;\n" +
+	"\vcaptured_at\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"capturedAt\x12/\n" +
+	"\aroutine\x18\x02 \x01(\v2\x15.domain.DeviceRoutineR\aroutine*R\n" +
+	"\fDeviceStatus\x12\x0f\n" +
+	"\vUNSPECIFIED\x10\x00\x12\v\n" +
+	"\aHEALTHY\x10\x01\x12\v\n" +
+	"\aWARNING\x10\x02\x12\t\n" +
+	"\x05ERROR\x10\x03\x12\f\n" +
+	"\bCRITICAL\x10\x04B\x12Z\x10lib/proto/gen/pbb\x06proto3"
 
 var (
 	file_device_proto_rawDescOnce sync.Once
@@ -204,20 +536,33 @@ func file_device_proto_rawDescGZIP() []byte {
 }
 
 var file_device_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_device_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_device_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
 var file_device_proto_goTypes = []any{
 	(DeviceStatus)(0),             // 0: domain.DeviceStatus
 	(*DeviceRoutine)(nil),         // 1: domain.DeviceRoutine
-	(*timestamppb.Timestamp)(nil), // 2: google.protobuf.Timestamp
+	(*DeviceStatusChanged)(nil),   // 2: domain.DeviceStatusChanged
+	(*DeviceAlert)(nil),           // 3: domain.DeviceAlert
+	(*DeviceHeartbeatMissed)(nil), // 4: domain.DeviceHeartbeatMissed
+	(*CapturedRoutine)(nil),       // 5: domain.CapturedRoutine
+	(*timestamppb.Timestamp)(nil), // 6: google.protobuf.Timestamp
 }
 var file_device_proto_depIdxs = []int32{
-	0, // 0: domain.DeviceRoutine.status:type_name -> domain.DeviceStatus
-	2, // 1: domain.DeviceRoutine.dispatched_at:type_name -> google.protobuf.Timestamp
-	2, // [2:2] is the sub-list for method output_type
-	2, // [2:2] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	0,  // 0: domain.DeviceRoutine.status:type_name -> domain.DeviceStatus
+	6,  // 1: domain.DeviceRoutine.dispatched_at:type_name -> google.protobuf.Timestamp
+	0,  // 2: domain.DeviceStatusChanged.previous_status:type_name -> domain.DeviceStatus
+	0,  // 3: domain.DeviceStatusChanged.current_status:type_name -> domain.DeviceStatus
+	6,  // 4: domain.DeviceStatusChanged.changed_at:type_name -> google.protobuf.Timestamp
+	6,  // 5: domain.DeviceAlert.fired_at:type_name -> google.protobuf.Timestamp
+	6,  // 6: domain.DeviceAlert.resolved_at:type_name -> google.protobuf.Timestamp
+	6,  // 7: domain.DeviceHeartbeatMissed.last_seen_at:type_name -> google.protobuf.Timestamp
+	6,  // 8: domain.DeviceHeartbeatMissed.detected_at:type_name -> google.protobuf.Timestamp
+	6,  // 9: domain.CapturedRoutine.captured_at:type_name -> google.protobuf.Timestamp
+	1,  // 10: domain.CapturedRoutine.routine:type_name -> domain.DeviceRoutine
+	11, // [11:11] is the sub-list for method output_type
+	11, // [11:11] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
 }
 
 func init() { file_device_proto_init() }
@@ -231,7 +576,7 @@ func file_device_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_device_proto_rawDesc), len(file_device_proto_rawDesc)),
 			NumEnums:      1,
-			NumMessages:   1,
+			NumMessages:   5,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
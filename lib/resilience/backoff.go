@@ -0,0 +1,19 @@
+package resilience
+
+import "time"
+
+// Backoff returns the exponential delay for the given 1-based attempt,
+// starting at base and capped at max when max is set.
+func Backoff(base, max time.Duration, attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := base << (attempt - 1)
+
+	if max > 0 && (delay > max || delay <= 0) {
+		return max
+	}
+
+	return delay
+}
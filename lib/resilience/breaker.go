@@ -0,0 +1,205 @@
+package resilience
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type BreakerState int
+
+const (
+	StateClosed BreakerState = iota
+	StateHalfOpen
+	StateOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half_open"
+	case StateOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	defaultFailureThreshold = 5
+	defaultOpenTimeout      = 30 * time.Second
+	defaultHalfOpenCalls    = 1
+)
+
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures that opens the
+	// breaker. Defaults to 5.
+	FailureThreshold int
+	// OpenTimeout is how long the breaker rejects calls before letting trial
+	// calls through. Defaults to 30s.
+	OpenTimeout time.Duration
+	// HalfOpenCalls is the number of concurrent trial calls allowed while
+	// half-open. Defaults to 1.
+	HalfOpenCalls int
+	// IsFailure classifies errors. Defaults to counting every error except
+	// cancellation of the caller's context.
+	IsFailure func(err error) bool
+	// OnStateChange is called, with the breaker lock held, whenever the
+	// state changes.
+	OnStateChange func(from, to BreakerState)
+}
+
+func (c *BreakerConfig) failureThreshold() int {
+	if c.FailureThreshold <= 0 {
+		return defaultFailureThreshold
+	}
+
+	return c.FailureThreshold
+}
+
+func (c *BreakerConfig) openTimeout() time.Duration {
+	if c.OpenTimeout <= 0 {
+		return defaultOpenTimeout
+	}
+
+	return c.OpenTimeout
+}
+
+func (c *BreakerConfig) halfOpenCalls() int {
+	if c.HalfOpenCalls <= 0 {
+		return defaultHalfOpenCalls
+	}
+
+	return c.HalfOpenCalls
+}
+
+// CircuitBreaker stops calling a failing dependency. After FailureThreshold
+// consecutive failures it opens and rejects calls with ErrCircuitOpen; once
+// OpenTimeout passes it half-opens, and a successful trial call closes it
+// again while a failed one reopens it.
+type CircuitBreaker struct {
+	mu       sync.Mutex
+	cfg      BreakerConfig
+	state    BreakerState
+	failures int
+	trials   int
+	openedAt time.Time
+	now      func() time.Time
+}
+
+func NewCircuitBreaker(cfg BreakerConfig) *CircuitBreaker {
+	return &CircuitBreaker{
+		cfg: cfg,
+		now: time.Now,
+	}
+}
+
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.expireOpen()
+
+	return b.state
+}
+
+func (b *CircuitBreaker) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	trial, err := b.acquire()
+	if err != nil {
+		return err
+	}
+
+	err = fn(ctx)
+
+	b.release(ctx, trial, err)
+
+	return err
+}
+
+// acquire admits a call and reports whether it took a half-open trial slot.
+func (b *CircuitBreaker) acquire() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.expireOpen()
+
+	switch b.state {
+	case StateOpen:
+		return false, ErrCircuitOpen
+	case StateHalfOpen:
+		if b.trials >= b.cfg.halfOpenCalls() {
+			return false, ErrCircuitOpen
+		}
+
+		b.trials++
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// release records the outcome of a call. Only trial calls give back a slot,
+// so calls admitted before the breaker half-opened do not free one.
+func (b *CircuitBreaker) release(ctx context.Context, trial bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if trial && b.trials > 0 {
+		b.trials--
+	}
+
+	if !b.isFailure(ctx, err) {
+		b.failures = 0
+
+		if b.state == StateHalfOpen {
+			b.transition(StateClosed)
+		}
+
+		return
+	}
+
+	b.failures++
+
+	if b.state == StateHalfOpen || b.failures >= b.cfg.failureThreshold() {
+		b.openedAt = b.now()
+		b.transition(StateOpen)
+	}
+}
+
+func (b *CircuitBreaker) isFailure(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+
+	if b.cfg.IsFailure == nil {
+		return true
+	}
+
+	return b.cfg.IsFailure(err)
+}
+
+// expireOpen half-opens the breaker once its open timeout has passed. It must
+// be called with the lock held.
+func (b *CircuitBreaker) expireOpen() {
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.cfg.openTimeout() {
+		b.trials = 0
+		b.transition(StateHalfOpen)
+	}
+}
+
+func (b *CircuitBreaker) transition(to BreakerState) {
+	from := b.state
+	if from == to {
+		return
+	}
+
+	b.state = to
+	b.failures = 0
+
+	if b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(from, to)
+	}
+}
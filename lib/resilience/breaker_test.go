@@ -0,0 +1,206 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errDependency = errors.New("dependency failed")
+
+// newTestBreaker returns a breaker on a manually advanced clock.
+func newTestBreaker(cfg BreakerConfig) (*CircuitBreaker, *time.Time) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	b := NewCircuitBreaker(cfg)
+	b.now = func() time.Time { return now }
+
+	return b, &now
+}
+
+func fail(context.Context) error    { return errDependency }
+func succeed(context.Context) error { return nil }
+
+func openBreaker(t *testing.T, b *CircuitBreaker, failures int) {
+	t.Helper()
+
+	for range failures {
+		b.Execute(context.Background(), fail)
+	}
+
+	if got := b.State(); got != StateOpen {
+		t.Fatalf("state after %d failures = %s, want %s", failures, got, StateOpen)
+	}
+}
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	b, _ := newTestBreaker(BreakerConfig{FailureThreshold: 3, OpenTimeout: time.Minute})
+
+	b.Execute(context.Background(), fail)
+	b.Execute(context.Background(), fail)
+	b.Execute(context.Background(), succeed)
+	b.Execute(context.Background(), fail)
+	b.Execute(context.Background(), fail)
+
+	if got := b.State(); got != StateClosed {
+		t.Fatalf("state = %s, want %s after a success reset the failures", got, StateClosed)
+	}
+
+	b.Execute(context.Background(), fail)
+
+	if got := b.State(); got != StateOpen {
+		t.Fatalf("state = %s, want %s", got, StateOpen)
+	}
+
+	called := false
+	err := b.Execute(context.Background(), func(context.Context) error {
+		called = true
+		return nil
+	})
+
+	if !errors.Is(err, ErrCircuitOpen) || called {
+		t.Fatalf("open breaker returned %v and called fn = %t, want %v without calling", err, called, ErrCircuitOpen)
+	}
+}
+
+func TestBreakerIgnoresCallerCancellation(t *testing.T) {
+	b, _ := newTestBreaker(BreakerConfig{FailureThreshold: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	b.Execute(ctx, func(ctx context.Context) error { return ctx.Err() })
+
+	if got := b.State(); got != StateClosed {
+		t.Fatalf("state = %s, want %s", got, StateClosed)
+	}
+}
+
+func TestBreakerHalfOpenTrialSuccessCloses(t *testing.T) {
+	var transitions []BreakerState
+
+	b, now := newTestBreaker(BreakerConfig{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		OnStateChange:    func(_, to BreakerState) { transitions = append(transitions, to) },
+	})
+
+	openBreaker(t, b, 2)
+
+	*now = now.Add(time.Minute)
+
+	if got := b.State(); got != StateHalfOpen {
+		t.Fatalf("state after open timeout = %s, want %s", got, StateHalfOpen)
+	}
+
+	if err := b.Execute(context.Background(), succeed); err != nil {
+		t.Fatalf("trial call: %v", err)
+	}
+
+	if got := b.State(); got != StateClosed {
+		t.Fatalf("state after successful trial = %s, want %s", got, StateClosed)
+	}
+
+	want := []BreakerState{StateOpen, StateHalfOpen, StateClosed}
+	if len(transitions) != len(want) {
+		t.Fatalf("transitions = %v, want %v", transitions, want)
+	}
+
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Fatalf("transitions = %v, want %v", transitions, want)
+		}
+	}
+}
+
+func TestBreakerHalfOpenTrialFailureReopens(t *testing.T) {
+	b, now := newTestBreaker(BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute})
+
+	openBreaker(t, b, 2)
+
+	*now = now.Add(time.Minute)
+
+	// a single failed trial reopens the breaker, below the threshold
+	if err := b.Execute(context.Background(), fail); !errors.Is(err, errDependency) {
+		t.Fatalf("trial call error = %v, want %v", err, errDependency)
+	}
+
+	if got := b.State(); got != StateOpen {
+		t.Fatalf("state after failed trial = %s, want %s", got, StateOpen)
+	}
+
+	// the open timeout restarts from the failed trial
+	*now = now.Add(30 * time.Second)
+
+	if err := b.Execute(context.Background(), succeed); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("call after reopening = %v, want %v", err, ErrCircuitOpen)
+	}
+}
+
+func TestBreakerHalfOpenLimitsTrialCalls(t *testing.T) {
+	b, now := newTestBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenCalls: 1})
+
+	openBreaker(t, b, 1)
+
+	*now = now.Add(time.Minute)
+
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	done := make(chan error, 1)
+
+	go func() {
+		done <- b.Execute(context.Background(), func(context.Context) error {
+			close(started)
+			<-finish
+			return nil
+		})
+	}()
+
+	<-started
+
+	if err := b.Execute(context.Background(), succeed); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second trial = %v, want %v while the first is in flight", err, ErrCircuitOpen)
+	}
+
+	close(finish)
+
+	if err := <-done; err != nil {
+		t.Fatalf("first trial: %v", err)
+	}
+
+	if got := b.State(); got != StateClosed {
+		t.Fatalf("state = %s, want %s", got, StateClosed)
+	}
+}
+
+func TestBreakerReleasesOnlyTrialSlots(t *testing.T) {
+	b, now := newTestBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenCalls: 1})
+
+	// a call admitted while closed is still in flight when the breaker
+	// opens and then half-opens
+	stale, err := b.acquire()
+	if err != nil || stale {
+		t.Fatalf("acquire while closed = (%t, %v), want a call without a trial slot", stale, err)
+	}
+
+	openBreaker(t, b, 1)
+	*now = now.Add(time.Minute)
+
+	trial, err := b.acquire()
+	if err != nil || !trial {
+		t.Fatalf("acquire while half-open = (%t, %v), want a trial slot", trial, err)
+	}
+
+	b.release(context.Background(), stale, errDependency)
+
+	if b.trials != 1 {
+		t.Fatalf("trial slots in use = %d, want 1 after a non-trial call finished", b.trials)
+	}
+
+	b.release(context.Background(), trial, nil)
+
+	if b.trials != 0 {
+		t.Fatalf("trial slots in use = %d, want 0 after the trial finished", b.trials)
+	}
+}
@@ -0,0 +1,57 @@
+package resilience
+
+import (
+	"context"
+	"time"
+)
+
+type BulkheadConfig struct {
+	// MaxConcurrent bounds the calls in flight. Zero disables the bulkhead.
+	MaxConcurrent int
+	// MaxWait bounds how long a call waits for a slot before failing with
+	// ErrBulkheadFull. Zero waits until the caller's context is done.
+	MaxWait time.Duration
+}
+
+// Bulkhead limits concurrent calls so a slow dependency cannot tie up every
+// worker.
+type Bulkhead struct {
+	slots   chan struct{}
+	maxWait time.Duration
+}
+
+func NewBulkhead(cfg BulkheadConfig) *Bulkhead {
+	b := &Bulkhead{maxWait: cfg.MaxWait}
+
+	if cfg.MaxConcurrent > 0 {
+		b.slots = make(chan struct{}, cfg.MaxConcurrent)
+	}
+
+	return b
+}
+
+func (b *Bulkhead) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	if b.slots == nil {
+		return fn(ctx)
+	}
+
+	var wait <-chan time.Time
+	if b.maxWait > 0 {
+		timer := time.NewTimer(b.maxWait)
+		defer timer.Stop()
+
+		wait = timer.C
+	}
+
+	select {
+	case b.slots <- struct{}{}:
+	case <-wait:
+		return ErrBulkheadFull
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	defer func() { <-b.slots }()
+
+	return fn(ctx)
+}
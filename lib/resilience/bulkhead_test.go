@@ -0,0 +1,85 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBulkheadRejectsCallsBeyondCapacity(t *testing.T) {
+	b := NewBulkhead(BulkheadConfig{MaxConcurrent: 1, MaxWait: 10 * time.Millisecond})
+
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	done := make(chan error, 1)
+
+	go func() {
+		done <- b.Execute(context.Background(), func(context.Context) error {
+			close(started)
+			<-finish
+			return nil
+		})
+	}()
+
+	<-started
+
+	if err := b.Execute(context.Background(), succeed); !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("call beyond capacity = %v, want %v", err, ErrBulkheadFull)
+	}
+
+	close(finish)
+
+	if err := <-done; err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+
+	// the slot is released once the first call returns
+	if err := b.Execute(context.Background(), succeed); err != nil {
+		t.Fatalf("call after release: %v", err)
+	}
+}
+
+func TestBulkheadWaitIsBoundedByContext(t *testing.T) {
+	b := NewBulkhead(BulkheadConfig{MaxConcurrent: 1})
+
+	finish := make(chan struct{})
+	defer close(finish)
+
+	started := make(chan struct{})
+	go b.Execute(context.Background(), func(context.Context) error {
+		close(started)
+		<-finish
+		return nil
+	})
+
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := b.Execute(ctx, succeed); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("waiting call = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestBulkheadDisabledWithoutLimit(t *testing.T) {
+	b := NewBulkhead(BulkheadConfig{})
+
+	finish := make(chan struct{})
+	defer close(finish)
+
+	for range 3 {
+		started := make(chan struct{})
+		go b.Execute(context.Background(), func(context.Context) error {
+			close(started)
+			<-finish
+			return nil
+		})
+		<-started
+	}
+
+	if err := b.Execute(context.Background(), succeed); err != nil {
+		t.Fatalf("unbounded bulkhead rejected a call: %v", err)
+	}
+}
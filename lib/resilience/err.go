@@ -0,0 +1,29 @@
+package resilience
+
+import "errors"
+
+var (
+	ErrCircuitOpen  = errors.New("circuit breaker is open")
+	ErrBulkheadFull = errors.New("bulkhead is full")
+)
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
@@ -0,0 +1,53 @@
+package resilience
+
+import (
+	"context"
+	"time"
+)
+
+type Config struct {
+	// Timeout bounds each attempt, within the caller's own deadline. Zero
+	// disables the per-attempt timeout.
+	Timeout  time.Duration
+	Retry    RetryConfig
+	Breaker  BreakerConfig
+	Bulkhead BulkheadConfig
+}
+
+// Policy combines a bulkhead, retries, a circuit breaker and a per-attempt
+// timeout. A call takes one bulkhead slot for all of its attempts, and every
+// attempt goes through the breaker, so an opening breaker stops the retries.
+type Policy struct {
+	cfg      Config
+	breaker  *CircuitBreaker
+	bulkhead *Bulkhead
+}
+
+func New(cfg Config) *Policy {
+	return &Policy{
+		cfg:      cfg,
+		breaker:  NewCircuitBreaker(cfg.Breaker),
+		bulkhead: NewBulkhead(cfg.Bulkhead),
+	}
+}
+
+func (p *Policy) Breaker() *CircuitBreaker {
+	return p.breaker
+}
+
+func (p *Policy) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	return p.bulkhead.Execute(ctx, func(ctx context.Context) error {
+		return Retry(ctx, p.cfg.Retry, func(ctx context.Context) error {
+			return p.breaker.Execute(ctx, func(ctx context.Context) error {
+				if p.cfg.Timeout <= 0 {
+					return fn(ctx)
+				}
+
+				attemptCtx, cancel := context.WithTimeout(ctx, p.cfg.Timeout)
+				defer cancel()
+
+				return fn(attemptCtx)
+			})
+		})
+	})
+}
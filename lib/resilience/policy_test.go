@@ -0,0 +1,72 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPolicyTimesOutEachAttempt(t *testing.T) {
+	p := New(Config{
+		Timeout: 10 * time.Millisecond,
+		Retry:   RetryConfig{MaxAttempts: 2, BaseDelay: time.Millisecond},
+	})
+
+	attempts := 0
+	err := p.Execute(context.Background(), func(ctx context.Context) error {
+		attempts++
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Execute error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// an attempt timing out is retried, since the caller's context is live
+	if attempts != 2 {
+		t.Fatalf("attempts = %d, want 2", attempts)
+	}
+}
+
+func TestPolicyTimeoutKeepsCallerDeadline(t *testing.T) {
+	p := New(Config{Timeout: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := p.Execute(ctx, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Execute error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestPolicyOpeningBreakerStopsRetries(t *testing.T) {
+	p := New(Config{
+		Retry:   RetryConfig{MaxAttempts: 5, BaseDelay: time.Millisecond},
+		Breaker: BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute},
+	})
+
+	attempts := 0
+	err := p.Execute(context.Background(), func(context.Context) error {
+		attempts++
+		return errDependency
+	})
+
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Execute error = %v, want %v", err, ErrCircuitOpen)
+	}
+
+	if attempts != 2 {
+		t.Fatalf("attempts = %d, want 2", attempts)
+	}
+
+	if got := p.Breaker().State(); got != StateOpen {
+		t.Fatalf("breaker state = %s, want %s", got, StateOpen)
+	}
+}
@@ -0,0 +1,88 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	defaultMaxAttempts = 3
+	defaultBaseDelay   = 100 * time.Millisecond
+)
+
+type RetryConfig struct {
+	// MaxAttempts bounds the number of calls, including the first. Defaults
+	// to 3.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled on each
+	// subsequent one. Defaults to 100ms.
+	BaseDelay time.Duration
+	// MaxDelay caps the retry delay when set.
+	MaxDelay time.Duration
+	// Retryable classifies errors. Errors marked Permanent, open breakers,
+	// full bulkheads and cancellation of the caller's context are never
+	// retried. Defaults to retrying every other error.
+	Retryable func(err error) bool
+	// OnRetry is called before sleeping ahead of each retry.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+func (c *RetryConfig) maxAttempts() int {
+	if c.MaxAttempts <= 0 {
+		return defaultMaxAttempts
+	}
+
+	return c.MaxAttempts
+}
+
+func (c *RetryConfig) baseDelay() time.Duration {
+	if c.BaseDelay <= 0 {
+		return defaultBaseDelay
+	}
+
+	return c.BaseDelay
+}
+
+func (c *RetryConfig) retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || IsPermanent(err) || errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrBulkheadFull) {
+		return false
+	}
+
+	if c.Retryable == nil {
+		return true
+	}
+
+	return c.Retryable(err)
+}
+
+// Retry calls fn until it succeeds, returns a non-retryable error, attempts
+// run out or ctx is done. It returns the last error.
+func Retry(ctx context.Context, cfg RetryConfig, fn func(ctx context.Context) error) error {
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+
+		if attempt >= cfg.maxAttempts() || !cfg.retryable(ctx, err) {
+			return err
+		}
+
+		delay := Backoff(cfg.baseDelay(), cfg.MaxDelay, attempt)
+
+		if cfg.OnRetry != nil {
+			cfg.OnRetry(attempt, err, delay)
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      RetryConfig
+		errs     []error
+		wantErr  error
+		wantCall int
+	}{
+		{
+			name:     "succeeds first time",
+			errs:     []error{nil},
+			wantCall: 1,
+		},
+		{
+			name:     "succeeds after transient failures",
+			cfg:      RetryConfig{MaxAttempts: 3},
+			errs:     []error{errDependency, errDependency, nil},
+			wantCall: 3,
+		},
+		{
+			name:     "gives up after max attempts",
+			cfg:      RetryConfig{MaxAttempts: 2},
+			errs:     []error{errDependency, errDependency, nil},
+			wantErr:  errDependency,
+			wantCall: 2,
+		},
+		{
+			name:     "stops on permanent errors",
+			errs:     []error{Permanent(errDependency), nil},
+			wantErr:  errDependency,
+			wantCall: 1,
+		},
+		{
+			name:     "stops when the circuit is open",
+			errs:     []error{ErrCircuitOpen, nil},
+			wantErr:  ErrCircuitOpen,
+			wantCall: 1,
+		},
+		{
+			name:     "stops when the bulkhead is full",
+			errs:     []error{ErrBulkheadFull, nil},
+			wantErr:  ErrBulkheadFull,
+			wantCall: 1,
+		},
+		{
+			name:     "stops on errors classified as not retryable",
+			cfg:      RetryConfig{Retryable: func(err error) bool { return !errors.Is(err, errDependency) }},
+			errs:     []error{errDependency, nil},
+			wantErr:  errDependency,
+			wantCall: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.BaseDelay = time.Millisecond
+
+			calls := 0
+			err := Retry(context.Background(), tt.cfg, func(context.Context) error {
+				err := tt.errs[calls]
+				calls++
+				return err
+			})
+
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("Retry error = %v, want %v", err, tt.wantErr)
+			}
+
+			if calls != tt.wantCall {
+				t.Fatalf("calls = %d, want %d", calls, tt.wantCall)
+			}
+		})
+	}
+}
+
+func TestRetryReportsBackoffDelays(t *testing.T) {
+	var delays []time.Duration
+
+	cfg := RetryConfig{
+		MaxAttempts: 4,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    3 * time.Millisecond,
+		OnRetry: func(_ int, _ error, delay time.Duration) {
+			delays = append(delays, delay)
+		},
+	}
+
+	Retry(context.Background(), cfg, fail)
+
+	want := []time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond}
+	if len(delays) != len(want) {
+		t.Fatalf("delays = %v, want %v", delays, want)
+	}
+
+	for i := range want {
+		if delays[i] != want[i] {
+			t.Fatalf("delays = %v, want %v", delays, want)
+		}
+	}
+}
+
+func TestRetryStopsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := 0
+	err := Retry(ctx, RetryConfig{MaxAttempts: 5, BaseDelay: time.Hour}, func(context.Context) error {
+		calls++
+		cancel()
+		return errDependency
+	})
+
+	if !errors.Is(err, errDependency) || calls != 1 {
+		t.Fatalf("Retry = (%v, %d calls), want the last error after 1 call", err, calls)
+	}
+}
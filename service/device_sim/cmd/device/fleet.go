@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/core/random"
+	"github.com/charmingruby/devicio/service/device_sim/internal/device"
+	"github.com/charmingruby/devicio/service/device_sim/pkg/instrumentation"
+)
+
+// runFleet has every device in the fleet report every interval, give or take
+// up to jitter, until duration elapses or ctx is cancelled. Devices start at
+// random offsets within the first interval so reports are spread out. Each
+// device draws from its own source derived from rng, so a seed replays the
+// same reports for every device.
+func runFleet(ctx context.Context, svc *device.Service, fleet []device.Device, rng *random.Rand, interval, jitter, duration time.Duration) error {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "main.runFleet")
+	defer complete()
+
+	// Reports in flight when the run stops are allowed to finish.
+	publishCtx := context.WithoutCancel(ctx)
+
+	if duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, duration)
+		defer cancel()
+	}
+
+	instrumentation.Logger.Info("Starting device fleet",
+		"devices", len(fleet),
+		"interval", interval,
+		"jitter", jitter,
+		"duration", duration,
+	)
+
+	var (
+		wg           sync.WaitGroup
+		mu           sync.Mutex
+		successCount int
+		errorCount   int
+	)
+
+	for i, d := range fleet {
+		wg.Add(1)
+
+		rng := rng.Derive(int64(i))
+		svc := svc.WithRand(rng)
+
+		go func() {
+			defer wg.Done()
+
+			instrumentation.Logger.Debug("Device online", "device_id", d.ID, "name", d.Name, "area", d.Area)
+
+			wait := time.Duration(rng.Int63n(int64(interval)))
+
+			for {
+				timer := time.NewTimer(wait)
+
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				}
+
+				err := svc.DispatchRoutineMessage(publishCtx, d)
+
+				mu.Lock()
+				if err != nil {
+					errorCount++
+				} else {
+					successCount++
+				}
+				mu.Unlock()
+
+				wait = jittered(rng, interval, jitter)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	instrumentation.Logger.Info("Fleet execution summary",
+		"total_jobs", successCount+errorCount,
+		"successful_jobs", successCount,
+		"failed_jobs", errorCount,
+	)
+
+	if errorCount > 0 {
+		return fmt.Errorf("encountered %d errors during processing", errorCount)
+	}
+
+	return nil
+}
+
+// jittered returns interval shifted by a uniform offset in [-jitter, jitter],
+// never dropping below a millisecond.
+func jittered(rng *random.Rand, interval, jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return interval
+	}
+
+	offset := time.Duration(rng.Int63n(2*int64(jitter)+1)) - jitter
+
+	return max(interval+offset, time.Millisecond)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/core/random"
+	"github.com/charmingruby/devicio/service/device_sim/internal/device"
+	"github.com/charmingruby/devicio/service/device_sim/internal/load"
+	"github.com/charmingruby/devicio/service/device_sim/pkg/instrumentation"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// rateUpdateInterval is how often the token bucket follows the profile.
+const rateUpdateInterval = 100 * time.Millisecond
+
+// runLoad publishes random routines for the fleet's devices, in turn, at the
+// rate described by the profile until its duration elapses or ctx is
+// cancelled, reporting throughput and latency every reportInterval. Each
+// routine draws from a source derived from rng for its place in the sequence,
+// so a seed replays the same routines whichever worker publishes them.
+func runLoad(ctx context.Context, svc *device.Service, profile load.Profile, fleet []device.Device, rng *random.Rand, concurrency int, reportInterval time.Duration) error {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "main.runLoad")
+	defer complete()
+
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+
+	// Publishes in flight when the run stops are allowed to finish.
+	publishCtx := context.WithoutCancel(ctx)
+
+	if profile.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, profile.Duration)
+		defer cancel()
+	}
+
+	instrumentation.Logger.Info("Starting rate controlled load",
+		"rate", profile.Rate,
+		"duration", profile.Duration,
+		"rampUp", profile.RampUp,
+		"rampDown", profile.RampDown,
+		"burstEvery", profile.BurstEvery,
+		"devices", len(fleet),
+	)
+
+	// Let the bucket hold about 10ms of the peak rate, so high rates are not
+	// capped by timer resolution.
+	peak := profile.Rate * max(profile.BurstMultiplier, 1)
+	bucket := load.NewTokenBucket(profile.RateAt(0), int(peak/100))
+	recorder := load.NewRecorder()
+
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for range max(concurrency, 1) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for seq := range jobs {
+				start := time.Now()
+				err := svc.WithRand(rng.Derive(int64(seq))).DispatchRoutineMessage(publishCtx, fleet[seq%len(fleet)])
+				recorder.Record(time.Since(start), err)
+			}
+		}()
+	}
+
+	stopPacing := make(chan struct{})
+	pacingStopped := make(chan struct{})
+
+	go func() {
+		defer close(pacingStopped)
+		paceLoad(profile, bucket, recorder, reportInterval, stopPacing)
+	}()
+
+	start := time.Now()
+
+dispatch:
+	for seq := 0; ; seq++ {
+		if err := bucket.Wait(ctx); err != nil {
+			break
+		}
+
+		select {
+		case jobs <- seq:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	close(stopPacing)
+	<-pacingStopped
+
+	if report := recorder.Flush(); report.Published+report.Failed > 0 {
+		logReport(report, profile.RateAt(time.Since(start)))
+	}
+
+	published, failed := recorder.Totals()
+	elapsed := time.Since(start)
+
+	instrumentation.Logger.Info("Load execution summary",
+		"elapsed", elapsed.Truncate(time.Millisecond).String(),
+		"total_jobs", published+failed,
+		"successful_jobs", published,
+		"failed_jobs", failed,
+		"throughput", fmt.Sprintf("%.1f", float64(published)/elapsed.Seconds()),
+	)
+
+	if failed > 0 {
+		return fmt.Errorf("encountered %d errors during processing", failed)
+	}
+
+	return nil
+}
+
+// paceLoad keeps the bucket on the profile's rate and periodically reports
+// on the publishes recorded so far.
+func paceLoad(profile load.Profile, bucket *load.TokenBucket, recorder *load.Recorder, reportInterval time.Duration, stop <-chan struct{}) {
+	start := time.Now()
+
+	rateTicker := time.NewTicker(rateUpdateInterval)
+	defer rateTicker.Stop()
+
+	reportTicker := time.NewTicker(reportInterval)
+	defer reportTicker.Stop()
+
+	targetRate := profile.RateAt(0)
+	setTargetRateMetric(targetRate)
+
+	for {
+		select {
+		case <-rateTicker.C:
+			targetRate = profile.RateAt(time.Since(start))
+			bucket.SetRate(targetRate)
+			setTargetRateMetric(targetRate)
+		case <-reportTicker.C:
+			logReport(recorder.Flush(), targetRate)
+		case <-stop:
+			return
+		}
+	}
+}
+
+func logReport(r load.Report, targetRate float64) {
+	instrumentation.Logger.Info("Load report",
+		"targetRate", fmt.Sprintf("%.1f", targetRate),
+		"throughput", fmt.Sprintf("%.1f", r.Throughput),
+		"published", r.Published,
+		"failed", r.Failed,
+		"p50", r.P50.String(),
+		"p95", r.P95.String(),
+		"p99", r.P99.String(),
+	)
+
+	gauges := []struct {
+		get   func() (prometheus.Gauge, error)
+		value float64
+	}{
+		{instrumentation.AchievedThroughputGaugeMetric, r.Throughput},
+		{instrumentation.PublishLatencyP50GaugeMetric, r.P50.Seconds()},
+		{instrumentation.PublishLatencyP95GaugeMetric, r.P95.Seconds()},
+		{instrumentation.PublishLatencyP99GaugeMetric, r.P99.Seconds()},
+	}
+
+	for _, g := range gauges {
+		gauge, err := g.get()
+		if err != nil {
+			instrumentation.Logger.Error("Failed to get load report gauge metric", "error", err)
+			continue
+		}
+
+		gauge.Set(g.value)
+	}
+}
+
+func setTargetRateMetric(rate float64) {
+	targetRateGaugeMetric, err := instrumentation.TargetRateGaugeMetric()
+	if err != nil {
+		instrumentation.Logger.Error("Failed to get target rate gauge metric", "error", err)
+		return
+	}
+
+	targetRateGaugeMetric.Set(rate)
+}
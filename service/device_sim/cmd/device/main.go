@@ -6,13 +6,24 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
-
+	"time"
+
+	"github.com/charmingruby/devicio/lib/core/random"
+	"github.com/charmingruby/devicio/lib/messaging"
+	"github.com/charmingruby/devicio/lib/messaging/capture"
+	"github.com/charmingruby/devicio/lib/messaging/kafka"
+	"github.com/charmingruby/devicio/lib/messaging/memory"
+	"github.com/charmingruby/devicio/lib/messaging/nats"
 	"github.com/charmingruby/devicio/lib/messaging/rabbitmq"
+	"github.com/charmingruby/devicio/lib/messaging/transport"
 	"github.com/charmingruby/devicio/lib/observability"
 	"github.com/charmingruby/devicio/service/device_sim/config"
 	"github.com/charmingruby/devicio/service/device_sim/internal/device"
+	"github.com/charmingruby/devicio/service/device_sim/internal/load"
+	"github.com/charmingruby/devicio/service/device_sim/internal/scenario"
 	"github.com/charmingruby/devicio/service/device_sim/pkg/instrumentation"
 )
 
@@ -21,15 +32,73 @@ func main() {
 
 	instrumentation.Logger.Info("Application started with default log level", "level", observability.LOG_LEVEL_DEFAULT)
 
+	// An optional leading command selects capture or replay instead of
+	// generating routines.
+	var command string
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		command = os.Args[1]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
 	recordsAmount := flag.Int("records", 10, "Amount of records to dispatch")
 	concurrency := flag.Int("concurrency", 5, "Amount of workers")
+	scenarioPath := flag.String("scenario", "", "Path to a YAML or JSON scenario file; replaces -records with the scenario's fleet")
+	rate := flag.Float64("rate", 0, "Target messages per second; enables continuous load mode, replacing -records")
+	duration := flag.Duration("duration", 0, "How long load and fleet modes run; zero runs until interrupted")
+	rampUp := flag.Duration("ramp-up", 0, "Time for load mode to climb from zero to -rate")
+	rampDown := flag.Duration("ramp-down", 0, "Time at the end of -duration for load mode to fall back to zero")
+	burstEvery := flag.Duration("burst-every", 0, "Period between load mode bursts; zero disables bursts")
+	burstDuration := flag.Duration("burst-duration", 0, "How long each load mode burst lasts")
+	burstMultiplier := flag.Float64("burst-multiplier", 2, "Rate multiplier applied during load mode bursts")
+	devices := flag.Int("devices", 100, "Size of the device fleet load mode reports for")
+	fleetSize := flag.Int("fleet", 0, "Size of a device fleet where every device reports periodically; enables fleet mode, replacing -records")
+	interval := flag.Duration("interval", 10*time.Second, "How often each device reports in fleet mode")
+	jitter := flag.Duration("jitter", 2*time.Second, "Maximum random deviation from -interval in fleet mode")
+	seed := flag.Int64("seed", 0, "Seed for every random choice, so runs can be replayed; overrides SEED and scenario seeds, zero picks one")
+	reportInterval := flag.Duration("report-interval", 5*time.Second, "How often load mode reports throughput and latency")
+	capturePath := flag.String("capture", "", "Path to record every published routine to, for a later replay")
+	outPath := flag.String("out", "capture.bin", "Path the capture command records consumed routines to")
+	inPath := flag.String("in", "capture.bin", "Path of the capture the replay command republishes")
+	speed := flag.Float64("speed", 1, "Replay speed relative to the original timing; zero replays as fast as possible")
+	restamp := flag.Bool("restamp", false, "Set each replayed routine's dispatch time to when it is replayed")
 	flag.Parse()
 
 	instrumentation.Logger.Info("Starting device simulator with configuration",
+		"command", command,
 		"records", *recordsAmount,
 		"concurrency", *concurrency,
+		"scenario", *scenarioPath,
+		"rate", *rate,
+		"fleet", *fleetSize,
 	)
 
+	modes := 0
+	for _, enabled := range []bool{*scenarioPath != "", *rate > 0, *fleetSize > 0} {
+		if enabled {
+			modes++
+		}
+	}
+
+	if modes > 1 {
+		instrumentation.Logger.Error("Only one of the -scenario, -rate and -fleet flags can be used")
+		os.Exit(1)
+	}
+
+	if *rate > 0 && *devices < 1 {
+		instrumentation.Logger.Error("Load mode requires a positive -devices")
+		os.Exit(1)
+	}
+
+	if *rate > 0 && *reportInterval <= 0 {
+		instrumentation.Logger.Error("Load mode requires a positive -report-interval")
+		os.Exit(1)
+	}
+
+	if *fleetSize > 0 && (*interval <= 0 || *jitter < 0 || *jitter >= *interval) {
+		instrumentation.Logger.Error("Fleet mode requires a positive -interval and a -jitter shorter than it")
+		os.Exit(1)
+	}
+
 	cfg, exists, err := config.New()
 	if err != nil {
 		instrumentation.Logger.Error("Failed to load configuration", "error", err)
@@ -59,25 +128,211 @@ func main() {
 
 	instrumentation.Logger.Info("Tracing system initialized successfully")
 
-	instrumentation.Logger.Info("Establishing RabbitMQ connection")
+	instrumentation.NewMeter()
+
+	go func() {
+		if err := instrumentation.RunMetricsServer(cfg.Custom.MetricsPort); err != nil {
+			instrumentation.Logger.Error("Failed to start Prometheus metrics server", "error", err)
+			os.Exit(1)
+		}
+	}()
 
-	queue, err := rabbitmq.New(instrumentation.Logger, instrumentation.Tracer, &rabbitmq.Config{
-		URL:       cfg.Custom.RabbitMQURL,
-		QueueName: cfg.Custom.RabbitMQQueueName,
-	})
+	instrumentation.Logger.Info("Prometheus metrics server started", "port", cfg.Custom.MetricsPort)
+
+	instrumentation.Logger.Info("Establishing messaging connection", "backend", cfg.Custom.MessagingBackend)
+
+	transportCfg := transport.Config{
+		Backend:     cfg.Custom.MessagingBackend,
+		ServiceName: cfg.ServiceName,
+		RabbitMQ: rabbitmq.Config{
+			URL:               cfg.Custom.RabbitMQURL,
+			QueueName:         cfg.Custom.RabbitMQQueueName,
+			Exchange:          cfg.Custom.RabbitMQExchange,
+			PublisherConfirms: cfg.Custom.RabbitMQPublisherConfirms,
+			PublishTimeout:    cfg.Custom.RabbitMQPublishTimeout,
+		},
+		NATS: nats.Config{
+			URL:     cfg.Custom.NATSURL,
+			Stream:  cfg.Custom.NATSStream,
+			Subject: cfg.Custom.NATSSubject,
+		},
+		Kafka: kafka.Config{
+			Brokers: cfg.Custom.KafkaBrokers,
+			Topic:   cfg.Custom.KafkaTopic,
+		},
+		Memory: memory.Config{
+			DeliveryDelay: cfg.Custom.MemoryDeliveryDelay,
+		},
+	}
+
+	if command == "capture" {
+		if err := captureTransport(&transportCfg); err != nil {
+			instrumentation.Logger.Error("Failed to configure capture consumer", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	queue, err := transport.New(instrumentation.Logger, instrumentation.Tracer, &transportCfg)
 	if err != nil {
-		instrumentation.Logger.Error("Failed to establish RabbitMQ connection", "error", err)
+		instrumentation.Logger.Error("Failed to establish messaging connection", "error", err)
 		os.Exit(1)
 	}
 
-	instrumentation.Logger.Info("RabbitMQ connection established successfully")
+	instrumentation.Logger.Info("Messaging connection established successfully")
+
+	switch command {
+	case "":
+	case "capture":
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+		err := runCapture(ctx, queue, *outPath)
+
+		stop()
+
+		if err != nil {
+			instrumentation.Logger.Error("Capture failed", "error", err)
+			shutdown(queue)
+			os.Exit(1)
+		}
+
+		instrumentation.Logger.Info("Capture completed successfully", "file", *outPath)
 
-	svc := device.NewService(queue)
+		shutdown(queue)
+		os.Exit(0)
+	case "replay":
+		if *speed < 0 {
+			instrumentation.Logger.Error("Replay requires a -speed of zero or more")
+			os.Exit(1)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+		err := runReplay(ctx, queue, *inPath, *speed, *restamp)
+
+		stop()
+
+		if err != nil {
+			instrumentation.Logger.Error("Replay failed", "error", err)
+			shutdown(queue)
+			os.Exit(1)
+		}
+
+		instrumentation.Logger.Info("Replay completed successfully")
+
+		shutdown(queue)
+		os.Exit(0)
+	default:
+		instrumentation.Logger.Error("Unknown command, expected capture or replay", "command", command)
+		os.Exit(1)
+	}
+
+	if *capturePath != "" {
+		file, err := os.Create(*capturePath)
+		if err != nil {
+			instrumentation.Logger.Error("Failed to create capture file", "error", err)
+			os.Exit(1)
+		}
+
+		queue = capture.NewTap(instrumentation.Logger, queue, capture.NewWriter(file))
+
+		instrumentation.Logger.Info("Capturing published routines", "file", *capturePath)
+	}
+
+	if *seed == 0 {
+		*seed = cfg.Custom.Seed
+	}
+
+	rng := random.New(*seed)
+
+	instrumentation.Logger.Info("Random source seeded", "seed", rng.Seed())
+
+	svc := device.NewService(queue, rng)
+
+	if *fleetSize > 0 {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+		err := runFleet(ctx, svc, device.NewFleet(*fleetSize, rng), rng, *interval, *jitter, *duration)
+
+		stop()
+
+		if err != nil {
+			instrumentation.Logger.Error("Fleet execution failed", "error", err)
+			shutdown(queue)
+			os.Exit(1)
+		}
+
+		instrumentation.Logger.Info("Fleet execution completed successfully")
+
+		shutdown(queue)
+		os.Exit(0)
+	}
+
+	if *rate > 0 {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+		err := runLoad(ctx, svc, load.Profile{
+			Rate:            *rate,
+			Duration:        *duration,
+			RampUp:          *rampUp,
+			RampDown:        *rampDown,
+			BurstEvery:      *burstEvery,
+			BurstDuration:   *burstDuration,
+			BurstMultiplier: *burstMultiplier,
+		}, device.NewFleet(*devices, rng), rng, *concurrency, *reportInterval)
+
+		stop()
+
+		if err != nil {
+			instrumentation.Logger.Error("Load execution failed", "error", err)
+			shutdown(queue)
+			os.Exit(1)
+		}
+
+		instrumentation.Logger.Info("Load execution completed successfully")
+
+		shutdown(queue)
+		os.Exit(0)
+	}
+
+	if *scenarioPath != "" {
+		instrumentation.Logger.Info("Loading scenario", "file", *scenarioPath)
+
+		s, err := scenario.Load(*scenarioPath)
+		if err != nil {
+			instrumentation.Logger.Error("Failed to load scenario", "error", err)
+			shutdown(queue)
+			os.Exit(1)
+		}
+
+		// An explicit seed takes precedence over the scenario's own.
+		scenarioSeed := s.Seed
+		if *seed != 0 || scenarioSeed == 0 {
+			scenarioSeed = rng.Seed()
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+		err = runScenario(ctx, svc, s, scenarioSeed, *concurrency)
+
+		stop()
+
+		if err != nil {
+			instrumentation.Logger.Error("Scenario execution failed", "error", err)
+			shutdown(queue)
+			os.Exit(1)
+		}
+
+		instrumentation.Logger.Info("Scenario execution completed successfully")
+
+		shutdown(queue)
+		os.Exit(0)
+	}
 
 	instrumentation.Logger.Info("Starting worker pool execution")
 
-	if err := runWorkerPool(svc, *recordsAmount, *concurrency); err != nil {
+	if err := runWorkerPool(svc, rng, *recordsAmount, *concurrency); err != nil {
 		instrumentation.Logger.Error("Worker pool execution failed", "error", err)
+		shutdown(queue)
 		os.Exit(1)
 	}
 
@@ -86,27 +341,34 @@ func main() {
 	gracefulShutdown(queue)
 }
 
-func gracefulShutdown(queue *rabbitmq.Client) {
+func gracefulShutdown(queue messaging.Queue) {
 	stopChan := make(chan os.Signal, 1)
 	signal.Notify(stopChan, os.Interrupt, syscall.SIGTERM)
 	<-stopChan
 
+	shutdown(queue)
+
+	os.Exit(0)
+}
+
+func shutdown(queue messaging.Queue) {
 	instrumentation.Logger.Info("Shutting down gracefully")
 
 	queue.Close()
 
-	instrumentation.Logger.Info("RabbitMQ connection closed")
+	instrumentation.Logger.Info("Messaging connection closed")
 
 	if err := instrumentation.Tracer.Close(); err != nil {
 		instrumentation.Logger.Error("Failed to close tracing system", "error", err)
 	}
 
 	instrumentation.Logger.Info("Tracing system closed")
-
-	os.Exit(0)
 }
 
-func runWorkerPool(svc *device.Service, recordsAmount, concurrency int) error {
+// runWorkerPool publishes recordsAmount random routines with concurrency
+// workers. Each record draws from a source derived from rng for its ID, so a
+// seed replays the same routines whichever worker publishes them.
+func runWorkerPool(svc *device.Service, rng *random.Rand, recordsAmount, concurrency int) error {
 	ctx, complete := instrumentation.Tracer.Span(context.Background(), "main.runWorkerPool")
 	defer complete()
 
@@ -122,7 +384,7 @@ func runWorkerPool(svc *device.Service, recordsAmount, concurrency int) error {
 	for i := range concurrency {
 		wg.Add(1)
 		instrumentation.Logger.Debug("Starting worker", "worker_id", i)
-		go worker(ctx, &wg, i, svc, jobs, results)
+		go worker(ctx, &wg, i, svc, rng, jobs, results)
 	}
 
 	go func() {
@@ -183,7 +445,7 @@ type workerResult struct {
 	err      error
 }
 
-func worker(ctx context.Context, wg *sync.WaitGroup, workerID int, svc *device.Service, jobs <-chan int, results chan<- workerResult) {
+func worker(ctx context.Context, wg *sync.WaitGroup, workerID int, svc *device.Service, rng *random.Rand, jobs <-chan int, results chan<- workerResult) {
 	ctx, complete := instrumentation.Tracer.Span(ctx, "main.worker")
 	defer complete()
 
@@ -202,7 +464,7 @@ func worker(ctx context.Context, wg *sync.WaitGroup, workerID int, svc *device.S
 				"record_id", recordID,
 			)
 
-			err := svc.DispatchRoutineMessage(ctx, device.Device{ID: fmt.Sprintf("device-%d", recordID)})
+			err := svc.WithRand(rng.Derive(int64(recordID))).DispatchRoutineMessage(ctx, device.Device{ID: fmt.Sprintf("device-%d", recordID)})
 			results <- workerResult{
 				workerID: workerID,
 				recordID: recordID,
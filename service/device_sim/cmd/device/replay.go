@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/messaging"
+	"github.com/charmingruby/devicio/lib/messaging/capture"
+	"github.com/charmingruby/devicio/lib/messaging/transport"
+	"github.com/charmingruby/devicio/service/device_sim/pkg/instrumentation"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var ErrCaptureExchange = errors.New("capturing from RabbitMQ requires RABBITMQ_EXCHANGE to bind a capture queue")
+
+// captureTransport gives the capture command a consumer of its own, so it
+// never competes with the processor for the routines it records: an
+// exclusive RabbitMQ queue bound to the routines exchange, or a separate
+// NATS durable or Kafka consumer group.
+func captureTransport(cfg *transport.Config) error {
+	if cfg.Backend == "" || cfg.Backend == transport.BackendRabbitMQ {
+		if cfg.RabbitMQ.Exchange == "" {
+			return ErrCaptureExchange
+		}
+
+		cfg.RabbitMQ.QueueName += ".capture"
+		cfg.RabbitMQ.Exclusive = true
+	}
+
+	cfg.NATS.Durable = cfg.NATS.Stream + "-capture"
+	cfg.Kafka.GroupID = cfg.ServiceName + "-capture"
+
+	return nil
+}
+
+// runCapture records every routine consumed from the queue to path until ctx
+// is cancelled. The queue must be configured through captureTransport.
+func runCapture(ctx context.Context, queue messaging.Queue, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create capture file: %w", err)
+	}
+
+	writer := capture.NewWriter(file)
+
+	instrumentation.Logger.Info("Capturing routines", "file", path)
+
+	err = queue.Subscribe(ctx, capture.Handler(writer))
+
+	if closeErr := writer.Close(); closeErr != nil {
+		return fmt.Errorf("failed to close capture file: %w", closeErr)
+	}
+
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	return nil
+}
+
+// runReplay republishes the routines captured in path. With a positive speed
+// the original spacing between routines is kept, divided by speed; zero
+// publishes as fast as possible. Restamp sets each routine's dispatch time to
+// when it is replayed instead of when it was captured.
+func runReplay(ctx context.Context, queue messaging.Queue, path string, speed float64, restamp bool) error {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "main.runReplay")
+	defer complete()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open capture file: %w", err)
+	}
+	defer file.Close()
+
+	reader := capture.NewReader(file)
+
+	instrumentation.Logger.Info("Replaying capture", "file", path, "speed", speed, "restamp", restamp)
+
+	var (
+		first        time.Time
+		start        = time.Now()
+		successCount int
+		errorCount   int
+	)
+
+	for {
+		captured, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		capturedAt := captured.GetCapturedAt().AsTime()
+		if first.IsZero() {
+			first = capturedAt
+		}
+
+		if speed > 0 {
+			timer := time.NewTimer(time.Until(start.Add(replayOffset(first, capturedAt, speed))))
+
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			}
+		} else if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		routine := captured.GetRoutine()
+		if restamp {
+			routine.DispatchedAt = timestamppb.Now()
+		}
+
+		if _, err := queue.Publish(ctx, routine); err != nil {
+			instrumentation.Logger.Warn("Failed to republish routine", "error", err, "device_id", routine.GetId())
+			errorCount++
+			continue
+		}
+
+		successCount++
+	}
+
+	instrumentation.Logger.Info("Replay execution summary",
+		"elapsed", time.Since(start).Truncate(time.Millisecond).String(),
+		"total_jobs", successCount+errorCount,
+		"successful_jobs", successCount,
+		"failed_jobs", errorCount,
+	)
+
+	if errorCount > 0 {
+		return fmt.Errorf("encountered %d errors during processing", errorCount)
+	}
+
+	return nil
+}
+
+// replayOffset returns how long after the replay starts a routine captured at
+// capturedAt is due, keeping its distance from the first routine divided by
+// speed. Speed must be positive.
+func replayOffset(first, capturedAt time.Time, speed float64) time.Duration {
+	return time.Duration(float64(capturedAt.Sub(first)) / speed)
+}
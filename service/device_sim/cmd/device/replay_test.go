@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReplayOffset(t *testing.T) {
+	first := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		speed   float64
+		want    time.Duration
+	}{
+		{name: "first routine", elapsed: 0, speed: 1, want: 0},
+		{name: "real time", elapsed: 3 * time.Second, speed: 1, want: 3 * time.Second},
+		{name: "double speed", elapsed: 3 * time.Second, speed: 2, want: 1500 * time.Millisecond},
+		{name: "ten times faster", elapsed: time.Minute, speed: 10, want: 6 * time.Second},
+		{name: "half speed", elapsed: 3 * time.Second, speed: 0.5, want: 6 * time.Second},
+		{name: "sub millisecond gap", elapsed: 500 * time.Microsecond, speed: 4, want: 125 * time.Microsecond},
+		{name: "out of order capture", elapsed: -2 * time.Second, speed: 2, want: -time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replayOffset(first, first.Add(tt.elapsed), tt.speed); got != tt.want {
+				t.Fatalf("replayOffset(%s later, speed %v) = %s, want %s", tt.elapsed, tt.speed, got, tt.want)
+			}
+		})
+	}
+}
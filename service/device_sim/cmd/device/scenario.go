@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/charmingruby/devicio/service/device_sim/internal/device"
+	"github.com/charmingruby/devicio/service/device_sim/internal/scenario"
+	"github.com/charmingruby/devicio/service/device_sim/pkg/instrumentation"
+)
+
+// runScenario dispatches every step of the scenario, publishing each step's
+// readings with up to concurrency workers before waiting for the next one.
+// Cancelling ctx stops the run after the step in progress.
+func runScenario(ctx context.Context, svc *device.Service, s *scenario.Scenario, seed int64, concurrency int) error {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "main.runScenario")
+	defer complete()
+
+	// Readings in flight when the run stops are allowed to finish.
+	publishCtx := context.WithoutCancel(ctx)
+
+	gen := scenario.NewGenerator(s, seed)
+
+	instrumentation.Logger.Info("Running scenario",
+		"name", s.Name,
+		"seed", seed,
+		"devices", gen.Devices(),
+		"steps", s.Steps,
+		"interval", s.Interval,
+	)
+
+	var (
+		successCount int
+		errorCount   int
+	)
+
+steps:
+	for step := 0; ; step++ {
+		readings, ok := gen.Next()
+		if !ok {
+			break
+		}
+
+		if step > 0 && s.Interval > 0 {
+			select {
+			case <-time.After(s.Interval):
+			case <-ctx.Done():
+				instrumentation.Logger.Warn("Scenario interrupted", "step", step)
+				break steps
+			}
+		}
+
+		failed := dispatchReadings(publishCtx, svc, readings, concurrency)
+
+		successCount += len(readings) - failed
+		errorCount += failed
+
+		instrumentation.Logger.Debug("Scenario step dispatched", "step", step, "readings", len(readings), "failed", failed)
+	}
+
+	instrumentation.Logger.Info("Scenario execution summary",
+		"total_jobs", successCount+errorCount,
+		"successful_jobs", successCount,
+		"failed_jobs", errorCount,
+	)
+
+	if errorCount > 0 {
+		return fmt.Errorf("encountered %d errors during processing", errorCount)
+	}
+
+	return nil
+}
+
+// dispatchReadings publishes readings with up to concurrency workers and
+// returns how many failed.
+func dispatchReadings(ctx context.Context, svc *device.Service, readings []device.Reading, concurrency int) int {
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed int
+	)
+
+	jobs := make(chan device.Reading)
+
+	for range max(concurrency, 1) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for reading := range jobs {
+				if err := svc.DispatchReading(ctx, reading); err != nil {
+					mu.Lock()
+					failed++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, reading := range readings {
+		jobs <- reading
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	return failed
+}
@@ -1,12 +1,26 @@
 package config
 
 import (
+	"time"
+
 	"github.com/charmingruby/devicio/lib/config"
 )
 
 type CustomConfig struct {
-	RabbitMQURL       string `env:"RABBITMQ_URL"`
-	RabbitMQQueueName string `env:"RABBITMQ_QUEUE_NAME"`
+	MessagingBackend          string        `env:"MESSAGING_BACKEND" envDefault:"rabbitmq"`
+	RabbitMQURL               string        `env:"RABBITMQ_URL"`
+	RabbitMQQueueName         string        `env:"RABBITMQ_QUEUE_NAME"`
+	RabbitMQExchange          string        `env:"RABBITMQ_EXCHANGE" envDefault:"device.routines"`
+	RabbitMQPublisherConfirms bool          `env:"RABBITMQ_PUBLISHER_CONFIRMS" envDefault:"true"`
+	RabbitMQPublishTimeout    time.Duration `env:"RABBITMQ_PUBLISH_TIMEOUT" envDefault:"5s"`
+	NATSURL                   string        `env:"NATS_URL"`
+	NATSStream                string        `env:"NATS_STREAM"`
+	NATSSubject               string        `env:"NATS_SUBJECT"`
+	KafkaBrokers              []string      `env:"KAFKA_BROKERS" envSeparator:","`
+	KafkaTopic                string        `env:"KAFKA_TOPIC"`
+	MemoryDeliveryDelay       time.Duration `env:"MEMORY_DELIVERY_DELAY"`
+	MetricsPort               string        `env:"METRICS_PORT" envDefault:"2113"`
+	Seed                      int64         `env:"SEED"`
 }
 
 func New() (config.Config[CustomConfig], bool, error) {
@@ -0,0 +1,31 @@
+package device
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/core/id"
+	"github.com/charmingruby/devicio/lib/core/random"
+)
+
+// NewFleet creates size devices with stable identities, spread across the
+// known areas round robin, so each one can report repeatedly as itself. IDs
+// are timestamped from the seed's epoch, a millisecond apart in fleet order,
+// and their random part is drawn from rng, so a seed always names the same
+// fleet.
+func NewFleet(size int, rng *random.Rand) []Device {
+	now := time.Now()
+	epoch := rng.Epoch()
+	fleet := make([]Device, 0, size)
+
+	for i := range size {
+		fleet = append(fleet, Device{
+			ID:        id.NewAt(epoch.Add(time.Duration(i)*time.Millisecond), rng),
+			Name:      fmt.Sprintf("device-%03d", i+1),
+			Area:      areas[i%len(areas)],
+			CreatedAt: now,
+		})
+	}
+
+	return fleet
+}
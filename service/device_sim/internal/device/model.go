@@ -1,6 +1,10 @@
 package device
 
-import "time"
+import (
+	"time"
+
+	pb "github.com/charmingruby/devicio/lib/proto/gen/pb"
+)
 
 type Device struct {
 	ID   string
@@ -9,3 +13,10 @@ type Device struct {
 	Area      string
 	CreatedAt time.Time
 }
+
+// Reading is the state a device reports in a single routine.
+type Reading struct {
+	Device      Device
+	Status      pb.DeviceStatus
+	Diagnostics string
+}
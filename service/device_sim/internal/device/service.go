@@ -2,17 +2,18 @@ package device
 
 import (
 	"context"
-	"math/rand"
 	"time"
 
-	"github.com/charmingruby/devicio/lib/messaging/rabbitmq"
+	"github.com/charmingruby/devicio/lib/core/random"
+	"github.com/charmingruby/devicio/lib/messaging"
 	pb "github.com/charmingruby/devicio/lib/proto/gen/pb"
 	"github.com/charmingruby/devicio/service/device_sim/pkg/instrumentation"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type Service struct {
-	queue *rabbitmq.Client
+	queue messaging.Queue
+	rng   *random.Rand
 }
 
 var diagnosticOptions = []string{
@@ -32,14 +33,39 @@ var statusOptions = []pb.DeviceStatus{
 
 var areas = []string{"A", "B", "C"}
 
-func NewService(queue *rabbitmq.Client) *Service {
-	return &Service{queue: queue}
+// NewService creates the service. All random choices are drawn from rng, so
+// a seeded source reproduces the same routines.
+func NewService(queue messaging.Queue, rng *random.Rand) *Service {
+	return &Service{queue: queue, rng: rng}
 }
 
+// WithRand returns a service publishing to the same queue that draws its
+// random choices from rng, for goroutines that need their own source.
+func (s *Service) WithRand(rng *random.Rand) *Service {
+	return &Service{queue: s.queue, rng: rng}
+}
+
+// DispatchRoutineMessage publishes a routine with a random status and
+// diagnostic. Devices without an area are given a random one.
 func (s *Service) DispatchRoutineMessage(ctx context.Context, device Device) error {
-	ctx, complete := instrumentation.Tracer.Span(ctx, "device.DispatchRoutineMessage")
+	if device.Area == "" {
+		device.Area = s.getRandomArea()
+	}
+
+	return s.DispatchReading(ctx, Reading{
+		Device:      device,
+		Status:      s.getRandomStatus(),
+		Diagnostics: s.getRandomDiagnostic(),
+	})
+}
+
+// DispatchReading publishes a routine carrying exactly the given reading.
+func (s *Service) DispatchReading(ctx context.Context, reading Reading) error {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "device.DispatchReading")
 	defer complete()
 
+	device := reading.Device
+
 	instrumentation.Logger.Debug("Dispatching routine message", "device_id", device.ID)
 
 	now := time.Now()
@@ -47,16 +73,40 @@ func (s *Service) DispatchRoutineMessage(ctx context.Context, device Device) err
 
 	routine := &pb.DeviceRoutine{
 		Id:           device.ID,
-		Status:       getRandomStatus(),
+		Status:       reading.Status,
 		Context:      "routine",
-		Diagnostics:  getRandomDiagnostic(),
-		Area:         getRandomArea(),
+		Diagnostics:  reading.Diagnostics,
+		Area:         device.Area,
 		DispatchedAt: timestamp,
 	}
 
-	if _, err := s.queue.Publish(ctx, routine); err != nil {
-		instrumentation.Logger.Warn("Failed to publish routine message", "error", err, "device_id", device.ID)
-		return err
+	start := time.Now()
+
+	if _, publishErr := s.queue.Publish(ctx, routine); publishErr != nil {
+		instrumentation.Logger.Warn("Failed to publish routine message", "error", publishErr, "device_id", device.ID)
+
+		errorsCounterListMetric, err := instrumentation.ErrorsCounterListMetric()
+		if err != nil {
+			instrumentation.Logger.Error("Failed to get errors counter list metric", "error", err)
+		} else {
+			errorsCounterListMetric.WithLabelValues("publish_error").Inc()
+		}
+
+		return publishErr
+	}
+
+	publishLatencyMetric, err := instrumentation.PublishLatencyHistogramMetric()
+	if err != nil {
+		instrumentation.Logger.Error("Failed to get publish latency histogram metric", "error", err)
+	} else {
+		publishLatencyMetric.Observe(time.Since(start).Seconds())
+	}
+
+	messagesPublishedMetric, err := instrumentation.MessagesPublishedCounterMetric()
+	if err != nil {
+		instrumentation.Logger.Error("Failed to get messages published counter metric", "error", err)
+	} else {
+		messagesPublishedMetric.Inc()
 	}
 
 	instrumentation.Logger.Debug("Routine message dispatched successfully", "device_id", device.ID)
@@ -64,24 +114,14 @@ func (s *Service) DispatchRoutineMessage(ctx context.Context, device Device) err
 	return nil
 }
 
-func getRandomDiagnostic() string {
-	options := make([]string, len(diagnosticOptions))
-	copy(options, diagnosticOptions)
-
-	rand.Shuffle(len(options), func(i, j int) {
-		options[i], options[j] = options[j], options[i]
-	})
-
-	return options[0]
+func (s *Service) getRandomDiagnostic() string {
+	return diagnosticOptions[s.rng.Intn(len(diagnosticOptions))]
 }
 
-func getRandomStatus() pb.DeviceStatus {
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	return statusOptions[r.Intn(len(statusOptions))]
+func (s *Service) getRandomStatus() pb.DeviceStatus {
+	return statusOptions[s.rng.Intn(len(statusOptions))]
 }
 
-func getRandomArea() string {
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-
-	return areas[r.Intn(len(areas))]
+func (s *Service) getRandomArea() string {
+	return areas[s.rng.Intn(len(areas))]
 }
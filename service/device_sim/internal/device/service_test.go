@@ -0,0 +1,135 @@
+package device
+
+import (
+	"context"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/charmingruby/devicio/lib/core/random"
+	"github.com/charmingruby/devicio/lib/messaging"
+	pb "github.com/charmingruby/devicio/lib/proto/gen/pb"
+	"github.com/charmingruby/devicio/service/device_sim/pkg/instrumentation"
+	"google.golang.org/protobuf/proto"
+)
+
+type nopTracer struct{}
+
+func (nopTracer) Span(ctx context.Context, _ string) (context.Context, func()) {
+	return ctx, func() {}
+}
+
+func (nopTracer) GetTraceIDFromContext(context.Context) string { return "" }
+
+func (nopTracer) Inject(context.Context, map[string]string) {}
+
+func (nopTracer) Extract(ctx context.Context, _ map[string]string) context.Context {
+	return ctx
+}
+
+func (nopTracer) Close() error { return nil }
+
+func TestMain(m *testing.M) {
+	instrumentation.NewLogger("error")
+	instrumentation.NewMeter()
+	instrumentation.Tracer = nopTracer{}
+
+	os.Exit(m.Run())
+}
+
+// recordingQueue keeps every published routine.
+type recordingQueue struct {
+	mu       sync.Mutex
+	routines []*pb.DeviceRoutine
+}
+
+func (q *recordingQueue) Publish(ctx context.Context, msg proto.Message) (context.Context, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.routines = append(q.routines, msg.(*pb.DeviceRoutine))
+
+	return ctx, nil
+}
+
+func (q *recordingQueue) Subscribe(context.Context, messaging.Handler) error { return nil }
+
+func (q *recordingQueue) Close() {}
+
+// run publishes reports routines for every device of a seeded fleet from
+// concurrent goroutines, each drawing from its own derived source, and
+// returns the published routines as id/area/status/diagnostics lines.
+func run(t *testing.T, seed int64, size, reports int) []string {
+	t.Helper()
+
+	queue := &recordingQueue{}
+	rng := random.New(seed)
+	svc := NewService(queue, rng)
+
+	var wg sync.WaitGroup
+
+	for i, d := range NewFleet(size, rng) {
+		wg.Add(1)
+
+		svc := svc.WithRand(rng.Derive(int64(i)))
+
+		go func() {
+			defer wg.Done()
+
+			for range reports {
+				if err := svc.DispatchRoutineMessage(context.Background(), d); err != nil {
+					t.Errorf("DispatchRoutineMessage: %v", err)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	lines := make([]string, 0, len(queue.routines))
+	for _, r := range queue.routines {
+		lines = append(lines, r.Id+" "+r.Area+" "+r.Status.String()+" "+r.Diagnostics)
+	}
+
+	// each device reports in order, so a stable sort by device keeps every
+	// device's own sequence while ignoring how the goroutines interleaved
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i][:26] < lines[j][:26] })
+
+	return lines
+}
+
+func TestSameSeedProducesIdenticalRuns(t *testing.T) {
+	first := run(t, 42, 8, 20)
+	second := run(t, 42, 8, 20)
+
+	if len(first) != len(second) {
+		t.Fatalf("runs published %d and %d routines", len(first), len(second))
+	}
+
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("routine %d = %q in the first run, %q in the second", i, first[i], second[i])
+		}
+	}
+
+	if other := run(t, 43, 8, 20); other[0] == first[0] {
+		t.Fatalf("seeds 42 and 43 published the same first routine %q", first[0])
+	}
+}
+
+func TestNewFleetIsReproducible(t *testing.T) {
+	first := NewFleet(5, random.New(42))
+	second := NewFleet(5, random.New(42))
+
+	for i := range first {
+		if first[i].ID != second[i].ID || first[i].Name != second[i].Name || first[i].Area != second[i].Area {
+			t.Fatalf("device %d = %+v, want %+v", i, second[i], first[i])
+		}
+
+		// IDs are timestamped a millisecond apart, so they sort in fleet order
+		if i > 0 && first[i].ID <= first[i-1].ID {
+			t.Fatalf("device %d ID %s sorts before %s", i, first[i].ID, first[i-1].ID)
+		}
+	}
+}
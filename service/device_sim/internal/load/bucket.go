@@ -0,0 +1,79 @@
+package load
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenBucket paces work to a rate of tokens per second, allowing up to
+// burst tokens to accumulate while callers are idle.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{
+		rate:  rate,
+		burst: float64(max(burst, 1)),
+		last:  time.Now(),
+	}
+}
+
+// SetRate changes the refill rate, keeping the tokens accumulated so far.
+func (b *TokenBucket) SetRate(rate float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(time.Now())
+	b.rate = rate
+}
+
+// Wait blocks until a token is available or ctx is done.
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	for {
+		delay := b.reserve()
+		if delay == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// reserve takes a token when one is available, otherwise it returns how long
+// until the next one is due.
+func (b *TokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(time.Now())
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+
+	// A paused bucket is polled so that a later SetRate takes effect.
+	if b.rate <= 0 {
+		return 100 * time.Millisecond
+	}
+
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+func (b *TokenBucket) refill(now time.Time) {
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
@@ -0,0 +1,79 @@
+package load
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketRefill(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    float64
+		burst   int
+		elapsed time.Duration
+		want    float64
+	}{
+		{name: "no time elapsed", rate: 10, burst: 5, elapsed: 0, want: 0},
+		{name: "partial token", rate: 10, burst: 5, elapsed: 50 * time.Millisecond, want: 0.5},
+		{name: "refills at rate", rate: 10, burst: 5, elapsed: 300 * time.Millisecond, want: 3},
+		{name: "capped at burst", rate: 10, burst: 5, elapsed: time.Minute, want: 5},
+		{name: "burst of at least one", rate: 10, burst: 0, elapsed: time.Minute, want: 1},
+		{name: "paused", rate: 0, burst: 5, elapsed: time.Minute, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewTokenBucket(tt.rate, tt.burst)
+
+			b.refill(b.last.Add(tt.elapsed))
+
+			if math.Abs(b.tokens-tt.want) > 1e-9 {
+				t.Fatalf("tokens = %v, want %v", b.tokens, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenBucketReserve(t *testing.T) {
+	b := NewTokenBucket(10, 1)
+
+	// the bucket starts empty, so the first token is a tenth of a second away
+	if delay := b.reserve(); delay <= 0 || delay > 100*time.Millisecond {
+		t.Fatalf("reserve on an empty bucket = %s, want up to 100ms", delay)
+	}
+
+	b.tokens = 1
+
+	if delay := b.reserve(); delay != 0 {
+		t.Fatalf("reserve with a token = %s, want 0", delay)
+	}
+
+	if b.tokens >= 1 {
+		t.Fatalf("tokens after reserve = %v, want the token to be taken", b.tokens)
+	}
+}
+
+func TestTokenBucketSetRateKeepsTokens(t *testing.T) {
+	b := NewTokenBucket(0, 5)
+	b.tokens = 2
+
+	b.SetRate(100)
+
+	if b.tokens < 2 || b.rate != 100 {
+		t.Fatalf("after SetRate tokens = %v and rate = %v, want at least 2 tokens at rate 100", b.tokens, b.rate)
+	}
+}
+
+func TestTokenBucketWaitStopsWithContext(t *testing.T) {
+	b := NewTokenBucket(0, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := b.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait on a paused bucket = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
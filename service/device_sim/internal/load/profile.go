@@ -0,0 +1,65 @@
+package load
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidProfile = errors.New("invalid load profile")
+
+// Profile describes the target publish rate over the run. The rate climbs
+// linearly from zero over RampUp, holds at Rate and falls back to zero over
+// the last RampDown of Duration. Every BurstEvery the rate is multiplied by
+// BurstMultiplier for BurstDuration.
+type Profile struct {
+	Rate            float64
+	Duration        time.Duration
+	RampUp          time.Duration
+	RampDown        time.Duration
+	BurstEvery      time.Duration
+	BurstDuration   time.Duration
+	BurstMultiplier float64
+}
+
+func (p Profile) Validate() error {
+	if p.Rate <= 0 {
+		return fmt.Errorf("%w: rate must be positive", ErrInvalidProfile)
+	}
+
+	if p.Duration < 0 || p.RampUp < 0 || p.RampDown < 0 || p.BurstEvery < 0 || p.BurstDuration < 0 {
+		return fmt.Errorf("%w: durations must not be negative", ErrInvalidProfile)
+	}
+
+	if p.RampDown > 0 && p.Duration == 0 {
+		return fmt.Errorf("%w: ramp down requires a duration", ErrInvalidProfile)
+	}
+
+	if p.Duration > 0 && p.RampUp+p.RampDown > p.Duration {
+		return fmt.Errorf("%w: ramps must fit within the duration", ErrInvalidProfile)
+	}
+
+	if p.BurstEvery > 0 && (p.BurstDuration == 0 || p.BurstDuration >= p.BurstEvery || p.BurstMultiplier <= 0) {
+		return fmt.Errorf("%w: bursts need a duration shorter than their period and a positive multiplier", ErrInvalidProfile)
+	}
+
+	return nil
+}
+
+// RateAt returns the target rate once elapsed has passed since the start.
+func (p Profile) RateAt(elapsed time.Duration) float64 {
+	rate := p.Rate
+
+	switch {
+	case p.RampUp > 0 && elapsed < p.RampUp:
+		rate *= float64(elapsed) / float64(p.RampUp)
+	case p.RampDown > 0 && elapsed > p.Duration-p.RampDown:
+		rate *= max(0, float64(p.Duration-elapsed)/float64(p.RampDown))
+	}
+
+	if p.BurstEvery > 0 && elapsed%p.BurstEvery >= p.BurstEvery-p.BurstDuration {
+		rate *= p.BurstMultiplier
+	}
+
+	return rate
+}
@@ -0,0 +1,109 @@
+package load
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestProfileRateAt(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile Profile
+		elapsed time.Duration
+		want    float64
+	}{
+		{name: "constant", profile: Profile{Rate: 100}, elapsed: time.Hour, want: 100},
+		{name: "ramp up start", profile: Profile{Rate: 100, RampUp: 10 * time.Second}, elapsed: 0, want: 0},
+		{name: "ramp up midway", profile: Profile{Rate: 100, RampUp: 10 * time.Second}, elapsed: 5 * time.Second, want: 50},
+		{name: "ramp up done", profile: Profile{Rate: 100, RampUp: 10 * time.Second}, elapsed: 10 * time.Second, want: 100},
+		{
+			name:    "before ramp down",
+			profile: Profile{Rate: 100, Duration: time.Minute, RampDown: 20 * time.Second},
+			elapsed: 40 * time.Second,
+			want:    100,
+		},
+		{
+			name:    "ramp down midway",
+			profile: Profile{Rate: 100, Duration: time.Minute, RampDown: 20 * time.Second},
+			elapsed: 50 * time.Second,
+			want:    50,
+		},
+		{
+			name:    "ramp down end",
+			profile: Profile{Rate: 100, Duration: time.Minute, RampDown: 20 * time.Second},
+			elapsed: time.Minute,
+			want:    0,
+		},
+		{
+			name:    "past the duration",
+			profile: Profile{Rate: 100, Duration: time.Minute, RampDown: 20 * time.Second},
+			elapsed: 2 * time.Minute,
+			want:    0,
+		},
+		{
+			name:    "outside a burst",
+			profile: Profile{Rate: 100, BurstEvery: 10 * time.Second, BurstDuration: 2 * time.Second, BurstMultiplier: 3},
+			elapsed: 7 * time.Second,
+			want:    100,
+		},
+		{
+			name:    "burst step",
+			profile: Profile{Rate: 100, BurstEvery: 10 * time.Second, BurstDuration: 2 * time.Second, BurstMultiplier: 3},
+			elapsed: 8 * time.Second,
+			want:    300,
+		},
+		{
+			name:    "burst ends with its period",
+			profile: Profile{Rate: 100, BurstEvery: 10 * time.Second, BurstDuration: 2 * time.Second, BurstMultiplier: 3},
+			elapsed: 10 * time.Second,
+			want:    100,
+		},
+		{
+			name:    "burst during ramp up",
+			profile: Profile{Rate: 100, RampUp: 20 * time.Second, BurstEvery: 10 * time.Second, BurstDuration: 2 * time.Second, BurstMultiplier: 2},
+			elapsed: 9 * time.Second,
+			want:    90,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.profile.RateAt(tt.elapsed); math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("RateAt(%s) = %v, want %v", tt.elapsed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfileValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile Profile
+		valid   bool
+	}{
+		{name: "constant", profile: Profile{Rate: 10}, valid: true},
+		{name: "full profile", profile: Profile{Rate: 10, Duration: time.Minute, RampUp: 10 * time.Second, RampDown: 10 * time.Second, BurstEvery: 10 * time.Second, BurstDuration: time.Second, BurstMultiplier: 2}, valid: true},
+		{name: "zero rate", profile: Profile{}},
+		{name: "negative duration", profile: Profile{Rate: 10, Duration: -time.Second}},
+		{name: "ramp down without duration", profile: Profile{Rate: 10, RampDown: time.Second}},
+		{name: "ramps longer than duration", profile: Profile{Rate: 10, Duration: 10 * time.Second, RampUp: 6 * time.Second, RampDown: 6 * time.Second}},
+		{name: "burst as long as its period", profile: Profile{Rate: 10, BurstEvery: time.Second, BurstDuration: time.Second, BurstMultiplier: 2}},
+		{name: "burst without multiplier", profile: Profile{Rate: 10, BurstEvery: 10 * time.Second, BurstDuration: time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.profile.Validate()
+
+			if tt.valid && err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+
+			if !tt.valid && !errors.Is(err, ErrInvalidProfile) {
+				t.Fatalf("Validate error = %v, want %v", err, ErrInvalidProfile)
+			}
+		})
+	}
+}
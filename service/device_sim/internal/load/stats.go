@@ -0,0 +1,93 @@
+package load
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Report summarizes the publishes recorded over an interval.
+type Report struct {
+	Interval   time.Duration
+	Published  int
+	Failed     int
+	Throughput float64
+	P50        time.Duration
+	P95        time.Duration
+	P99        time.Duration
+}
+
+// Recorder collects publish outcomes and latencies between reports.
+type Recorder struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+	failed    int
+	since     time.Time
+
+	totalPublished int
+	totalFailed    int
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{since: time.Now()}
+}
+
+func (r *Recorder) Record(latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		r.failed++
+		r.totalFailed++
+		return
+	}
+
+	r.latencies = append(r.latencies, latency)
+	r.totalPublished++
+}
+
+// Totals returns the publishes recorded since the recorder was created.
+func (r *Recorder) Totals() (published, failed int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.totalPublished, r.totalFailed
+}
+
+// Flush returns the report for the publishes since the previous flush and
+// starts a new interval.
+func (r *Recorder) Flush() Report {
+	r.mu.Lock()
+	latencies, failed, since := r.latencies, r.failed, r.since
+	r.latencies, r.failed, r.since = nil, 0, time.Now()
+	r.mu.Unlock()
+
+	slices.Sort(latencies)
+
+	report := Report{
+		Interval:  time.Since(since),
+		Published: len(latencies),
+		Failed:    failed,
+		P50:       percentile(latencies, 0.50),
+		P95:       percentile(latencies, 0.95),
+		P99:       percentile(latencies, 0.99),
+	}
+
+	if report.Interval > 0 {
+		report.Throughput = float64(report.Published) / report.Interval.Seconds()
+	}
+
+	return report
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
@@ -0,0 +1,71 @@
+package load
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	ms := func(values ...int) []time.Duration {
+		sorted := make([]time.Duration, len(values))
+		for i, v := range values {
+			sorted[i] = time.Duration(v) * time.Millisecond
+		}
+
+		return sorted
+	}
+
+	tests := []struct {
+		name   string
+		sorted []time.Duration
+		p      float64
+		want   time.Duration
+	}{
+		{name: "empty", sorted: nil, p: 0.5, want: 0},
+		{name: "single sample p50", sorted: ms(7), p: 0.50, want: 7 * time.Millisecond},
+		{name: "single sample p99", sorted: ms(7), p: 0.99, want: 7 * time.Millisecond},
+		{name: "zero percentile", sorted: ms(1, 2, 3), p: 0, want: time.Millisecond},
+		{name: "median of even count", sorted: ms(1, 2, 3, 4), p: 0.50, want: 2 * time.Millisecond},
+		{name: "median of odd count", sorted: ms(1, 2, 3, 4, 5), p: 0.50, want: 3 * time.Millisecond},
+		{name: "p95 of ten", sorted: ms(1, 2, 3, 4, 5, 6, 7, 8, 9, 10), p: 0.95, want: 10 * time.Millisecond},
+		{name: "p90 of ten", sorted: ms(1, 2, 3, 4, 5, 6, 7, 8, 9, 10), p: 0.90, want: 9 * time.Millisecond},
+		{name: "p100", sorted: ms(1, 2, 3), p: 1, want: 3 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := percentile(tt.sorted, tt.p); got != tt.want {
+				t.Fatalf("percentile(%v, %v) = %s, want %s", tt.sorted, tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecorderFlush(t *testing.T) {
+	r := NewRecorder()
+
+	for _, latency := range []time.Duration{30, 10, 20} {
+		r.Record(latency*time.Millisecond, nil)
+	}
+	r.Record(0, errors.New("publish failed"))
+
+	report := r.Flush()
+
+	if report.Published != 3 || report.Failed != 1 {
+		t.Fatalf("report = %d published and %d failed, want 3 and 1", report.Published, report.Failed)
+	}
+
+	if report.P50 != 20*time.Millisecond || report.P99 != 30*time.Millisecond {
+		t.Fatalf("report P50 = %s and P99 = %s, want 20ms and 30ms", report.P50, report.P99)
+	}
+
+	// the next interval starts empty while the totals keep counting
+	if empty := r.Flush(); empty.Published != 0 || empty.Failed != 0 || empty.P50 != 0 {
+		t.Fatalf("second flush = %+v, want an empty report", empty)
+	}
+
+	if published, failed := r.Totals(); published != 3 || failed != 1 {
+		t.Fatalf("totals = %d published and %d failed, want 3 and 1", published, failed)
+	}
+}
@@ -0,0 +1,91 @@
+package scenario
+
+import (
+	"fmt"
+	"math/rand"
+
+	pb "github.com/charmingruby/devicio/lib/proto/gen/pb"
+)
+
+// chain holds, for each status, the weights of the next status in the order
+// of statuses.
+type chain map[pb.DeviceStatus][]float64
+
+func (c Chain) compile() (chain, error) {
+	compiled := make(chain, len(c))
+
+	for from, to := range c {
+		fromStatus, err := parseStatus(from)
+		if err != nil {
+			return nil, err
+		}
+
+		weights := make([]float64, len(statuses))
+
+		var total float64
+
+		for name, weight := range to {
+			toStatus, err := parseStatus(name)
+			if err != nil {
+				return nil, err
+			}
+
+			if weight < 0 {
+				return nil, fmt.Errorf("%w: negative weight from %s to %s", ErrInvalidScenario, from, name)
+			}
+
+			weights[statusIndex(toStatus)] = weight
+			total += weight
+		}
+
+		if total == 0 {
+			return nil, fmt.Errorf("%w: no transitions with positive weight from %s", ErrInvalidScenario, from)
+		}
+
+		compiled[fromStatus] = weights
+	}
+
+	return compiled, nil
+}
+
+// next samples the status following current.
+func (c chain) next(rng *rand.Rand, current pb.DeviceStatus) pb.DeviceStatus {
+	weights, ok := c[current]
+	if !ok {
+		return current
+	}
+
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+
+	pick := rng.Float64() * total
+
+	for i, w := range weights {
+		if pick < w {
+			return statuses[i]
+		}
+
+		pick -= w
+	}
+
+	// Floating point rounding can leave pick just above the last weight.
+	for i := len(weights) - 1; i >= 0; i-- {
+		if weights[i] > 0 {
+			return statuses[i]
+		}
+	}
+
+	return current
+}
+
+func statusIndex(status pb.DeviceStatus) int {
+	for i, s := range statuses {
+		if s == status {
+			return i
+		}
+	}
+
+	return -1
+}
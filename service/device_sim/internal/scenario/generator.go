@@ -0,0 +1,129 @@
+package scenario
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+
+	pb "github.com/charmingruby/devicio/lib/proto/gen/pb"
+	"github.com/charmingruby/devicio/service/device_sim/internal/device"
+)
+
+type simulatedDevice struct {
+	device     device.Device
+	population *Population
+	status     pb.DeviceStatus
+}
+
+// Generator produces the readings of a scenario step by step. All random
+// choices come from a single source consumed in a fixed order, so a given
+// scenario and seed always produce the same readings.
+type Generator struct {
+	scenario *Scenario
+	rng      *rand.Rand
+	devices  []*simulatedDevice
+	step     int
+}
+
+func NewGenerator(s *Scenario, seed int64) *Generator {
+	g := &Generator{
+		scenario: s,
+		rng:      rand.New(rand.NewSource(seed)),
+	}
+
+	for i := range s.Populations {
+		p := &s.Populations[i]
+
+		for n := range p.Count {
+			id := fmt.Sprintf("%s-%d", p.Name, n+1)
+
+			g.devices = append(g.devices, &simulatedDevice{
+				device: device.Device{
+					ID:   id,
+					Name: id,
+					Area: p.Areas[n%len(p.Areas)],
+				},
+				population: p,
+				status:     p.initialStatus,
+			})
+		}
+	}
+
+	return g
+}
+
+// Devices returns the number of devices reporting on every step.
+func (g *Generator) Devices() int {
+	return len(g.devices)
+}
+
+// Next returns the readings for the next step, one per device, and false once
+// every step has been produced.
+func (g *Generator) Next() ([]device.Reading, bool) {
+	if g.step >= g.scenario.Steps {
+		return nil, false
+	}
+
+	readings := make([]device.Reading, 0, len(g.devices))
+
+	for _, d := range g.devices {
+		d.status = g.nextStatus(d)
+
+		readings = append(readings, device.Reading{
+			Device:      d.device,
+			Status:      d.status,
+			Diagnostics: g.diagnostic(d),
+		})
+	}
+
+	g.step++
+
+	return readings, true
+}
+
+func (g *Generator) nextStatus(d *simulatedDevice) pb.DeviceStatus {
+	c := d.population.chain
+
+	for i := range g.scenario.Timeline {
+		e := &g.scenario.Timeline[i]
+		if !e.active(g.step) || !e.matches(d) {
+			continue
+		}
+
+		if e.status != pb.DeviceStatus_UNSPECIFIED {
+			c = nil
+			d.status = e.status
+			continue
+		}
+
+		c = e.chain
+	}
+
+	// Devices report their initial or forced status as is.
+	if g.step == 0 || c == nil {
+		return d.status
+	}
+
+	return c.next(g.rng, d.status)
+}
+
+func (g *Generator) diagnostic(d *simulatedDevice) string {
+	name := d.status.String()
+
+	pool, ok := d.population.Diagnostics[name]
+	if !ok {
+		pool, ok = g.scenario.Diagnostics[name]
+	}
+
+	if !ok {
+		return fmt.Sprintf("Device reported %s", name)
+	}
+
+	return pool[g.rng.Intn(len(pool))]
+}
+
+func (e *Event) matches(d *simulatedDevice) bool {
+	return (e.Population == "" || e.Population == d.population.Name) &&
+		(len(e.Areas) == 0 || slices.Contains(e.Areas, d.device.Area)) &&
+		(len(e.Devices) == 0 || slices.Contains(e.Devices, d.device.ID))
+}
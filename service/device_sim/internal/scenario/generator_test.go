@@ -0,0 +1,92 @@
+package scenario
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	pb "github.com/charmingruby/devicio/lib/proto/gen/pb"
+	"github.com/charmingruby/devicio/service/device_sim/internal/device"
+)
+
+func generate(s *Scenario, seed int64) [][]device.Reading {
+	g := NewGenerator(s, seed)
+
+	var steps [][]device.Reading
+	for {
+		readings, ok := g.Next()
+		if !ok {
+			return steps
+		}
+
+		steps = append(steps, readings)
+	}
+}
+
+func TestGeneratorIsDeterministicForSeed(t *testing.T) {
+	s, err := Load("../../scenarios/area_degradation.example.yaml")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	first := generate(s, s.Seed)
+	second := generate(s, s.Seed)
+
+	if len(first) != s.Steps {
+		t.Fatalf("generated %d steps, want %d", len(first), s.Steps)
+	}
+
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("two runs with seed %d produced different readings", s.Seed)
+	}
+
+	if reflect.DeepEqual(first, generate(s, s.Seed+1)) {
+		t.Fatalf("runs with different seeds produced identical readings")
+	}
+}
+
+func TestChainCompileRejectsInvalidTables(t *testing.T) {
+	tests := map[string]Chain{
+		"unknown source":   {"BROKEN": {"HEALTHY": 1}},
+		"unknown target":   {"HEALTHY": {"BROKEN": 1}},
+		"negative weight":  {"HEALTHY": {"HEALTHY": 1, "ERROR": -1}},
+		"all zero weights": {"HEALTHY": {"HEALTHY": 0}},
+		"empty row":        {"HEALTHY": {}},
+	}
+
+	for name, c := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := c.compile(); !errors.Is(err, ErrInvalidScenario) {
+				t.Fatalf("compile error = %v, want %v", err, ErrInvalidScenario)
+			}
+		})
+	}
+}
+
+func TestChainNextFollowsWeights(t *testing.T) {
+	c, err := Chain{
+		"HEALTHY": {"ERROR": 1},
+		"ERROR":   {"HEALTHY": 0, "CRITICAL": 3},
+	}.compile()
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+
+	for range 100 {
+		if got := c.next(rng, pb.DeviceStatus_HEALTHY); got != pb.DeviceStatus_ERROR {
+			t.Fatalf("next(HEALTHY) = %s, want the only weighted status ERROR", got)
+		}
+
+		if got := c.next(rng, pb.DeviceStatus_ERROR); got != pb.DeviceStatus_CRITICAL {
+			t.Fatalf("next(ERROR) = %s, want CRITICAL over the zero weighted HEALTHY", got)
+		}
+	}
+
+	// statuses without a row stay as they are
+	if got := c.next(rng, pb.DeviceStatus_WARNING); got != pb.DeviceStatus_WARNING {
+		t.Fatalf("next(WARNING) = %s, want WARNING", got)
+	}
+}
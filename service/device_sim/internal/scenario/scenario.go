@@ -0,0 +1,240 @@
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	pb "github.com/charmingruby/devicio/lib/proto/gen/pb"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidScenario = errors.New("invalid scenario")
+
+// statuses fixes the order in which chain weights are sampled, so a seeded
+// run does not depend on map iteration order.
+var statuses = []pb.DeviceStatus{
+	pb.DeviceStatus_HEALTHY,
+	pb.DeviceStatus_WARNING,
+	pb.DeviceStatus_ERROR,
+	pb.DeviceStatus_CRITICAL,
+}
+
+// Chain maps a status to the relative weights of the statuses a device may
+// move to on its next routine, as in {HEALTHY: {HEALTHY: 95, WARNING: 5}}.
+// Statuses without an entry stay as they are.
+type Chain map[string]map[string]float64
+
+// Pools maps a status to the diagnostic texts a device may report with it.
+type Pools map[string][]string
+
+// Population is a group of Count devices sharing a status chain. Devices are
+// named <name>-<n> and assigned to Areas round robin.
+type Population struct {
+	Name          string   `yaml:"name"`
+	Count         int      `yaml:"count"`
+	Areas         []string `yaml:"areas"`
+	InitialStatus string   `yaml:"initial_status"`
+	Transitions   Chain    `yaml:"transitions"`
+	// Diagnostics overrides the scenario pools for this population.
+	Diagnostics Pools `yaml:"diagnostics"`
+
+	initialStatus pb.DeviceStatus
+	chain         chain
+}
+
+// Event changes the behaviour of the matching devices from step At until
+// step Until, or until the end of the run when Until is zero. Population,
+// Areas and Devices select devices; empty selectors match everything. An
+// event either forces a Status or replaces the Transitions chain. When events
+// overlap, the one declared last wins.
+type Event struct {
+	Name        string   `yaml:"name"`
+	At          int      `yaml:"at"`
+	Until       int      `yaml:"until"`
+	Population  string   `yaml:"population"`
+	Areas       []string `yaml:"areas"`
+	Devices     []string `yaml:"devices"`
+	Status      string   `yaml:"status"`
+	Transitions Chain    `yaml:"transitions"`
+
+	status pb.DeviceStatus
+	chain  chain
+}
+
+func (e *Event) active(step int) bool {
+	return step >= e.At && (e.Until == 0 || step < e.Until)
+}
+
+// Scenario is a simulated fleet run for Steps steps, each device sending one
+// routine per step and steps being Interval apart. It is read as YAML, so
+// JSON files are accepted as well.
+type Scenario struct {
+	Name string `yaml:"name"`
+	// Seed makes runs reproducible: the same scenario and seed produce the
+	// same sequence of routines. Zero picks a seed at random.
+	Seed        int64         `yaml:"seed"`
+	Steps       int           `yaml:"steps"`
+	Interval    time.Duration `yaml:"interval"`
+	Diagnostics Pools         `yaml:"diagnostics"`
+	Populations []Population  `yaml:"populations"`
+	Timeline    []Event       `yaml:"timeline"`
+}
+
+func Load(path string) (*Scenario, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario file: %w", err)
+	}
+
+	var s Scenario
+	if err := yaml.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario file: %w", err)
+	}
+
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (s *Scenario) validate() error {
+	if s.Steps < 1 {
+		return fmt.Errorf("%w: steps must be positive", ErrInvalidScenario)
+	}
+
+	if s.Interval < 0 {
+		return fmt.Errorf("%w: interval must not be negative", ErrInvalidScenario)
+	}
+
+	if len(s.Populations) == 0 {
+		return fmt.Errorf("%w: at least one population is required", ErrInvalidScenario)
+	}
+
+	if err := s.Diagnostics.validate(); err != nil {
+		return err
+	}
+
+	names := make(map[string]bool, len(s.Populations))
+
+	for i := range s.Populations {
+		p := &s.Populations[i]
+
+		if err := p.validate(); err != nil {
+			return err
+		}
+
+		if names[p.Name] {
+			return fmt.Errorf("%w: duplicate population %s", ErrInvalidScenario, p.Name)
+		}
+
+		names[p.Name] = true
+	}
+
+	for i := range s.Timeline {
+		e := &s.Timeline[i]
+
+		if err := e.validate(); err != nil {
+			return err
+		}
+
+		if e.Population != "" && !names[e.Population] {
+			return fmt.Errorf("%w: event %s: unknown population %s", ErrInvalidScenario, e.Name, e.Population)
+		}
+	}
+
+	return nil
+}
+
+func (p *Population) validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("%w: population missing name", ErrInvalidScenario)
+	}
+
+	if p.Count < 1 {
+		return fmt.Errorf("%w: population %s: count must be positive", ErrInvalidScenario, p.Name)
+	}
+
+	if len(p.Areas) == 0 {
+		return fmt.Errorf("%w: population %s: at least one area is required", ErrInvalidScenario, p.Name)
+	}
+
+	p.initialStatus = pb.DeviceStatus_HEALTHY
+
+	if p.InitialStatus != "" {
+		status, err := parseStatus(p.InitialStatus)
+		if err != nil {
+			return fmt.Errorf("population %s: %w", p.Name, err)
+		}
+
+		p.initialStatus = status
+	}
+
+	chain, err := p.Transitions.compile()
+	if err != nil {
+		return fmt.Errorf("population %s: %w", p.Name, err)
+	}
+
+	p.chain = chain
+
+	if err := p.Diagnostics.validate(); err != nil {
+		return fmt.Errorf("population %s: %w", p.Name, err)
+	}
+
+	return nil
+}
+
+func (e *Event) validate() error {
+	if e.At < 0 || (e.Until != 0 && e.Until <= e.At) {
+		return fmt.Errorf("%w: event %s: until must come after at", ErrInvalidScenario, e.Name)
+	}
+
+	if (e.Status == "") == (len(e.Transitions) == 0) {
+		return fmt.Errorf("%w: event %s: exactly one of status or transitions is required", ErrInvalidScenario, e.Name)
+	}
+
+	if e.Status != "" {
+		status, err := parseStatus(e.Status)
+		if err != nil {
+			return fmt.Errorf("event %s: %w", e.Name, err)
+		}
+
+		e.status = status
+
+		return nil
+	}
+
+	chain, err := e.Transitions.compile()
+	if err != nil {
+		return fmt.Errorf("event %s: %w", e.Name, err)
+	}
+
+	e.chain = chain
+
+	return nil
+}
+
+func (p Pools) validate() error {
+	for status, texts := range p {
+		if _, err := parseStatus(status); err != nil {
+			return err
+		}
+
+		if len(texts) == 0 {
+			return fmt.Errorf("%w: empty diagnostics pool for %s", ErrInvalidScenario, status)
+		}
+	}
+
+	return nil
+}
+
+func parseStatus(name string) (pb.DeviceStatus, error) {
+	v, ok := pb.DeviceStatus_value[name]
+	if !ok || pb.DeviceStatus(v) == pb.DeviceStatus_UNSPECIFIED {
+		return 0, fmt.Errorf("%w: unknown status %q", ErrInvalidScenario, name)
+	}
+
+	return pb.DeviceStatus(v), nil
+}
@@ -0,0 +1,129 @@
+package scenario
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeScenario(t *testing.T, body string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatalf("failed to write scenario: %v", err)
+	}
+
+	return path
+}
+
+func TestLoadExampleScenario(t *testing.T) {
+	s, err := Load("../../scenarios/area_degradation.example.yaml")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if s.Steps != 120 || len(s.Populations) != 2 || len(s.Timeline) != 2 {
+		t.Fatalf("loaded %d steps, %d populations and %d events, want 120, 2 and 2",
+			s.Steps, len(s.Populations), len(s.Timeline))
+	}
+}
+
+func TestLoadRejectsInvalidTransitionTables(t *testing.T) {
+	tests := map[string]string{
+		"unknown source status": `
+steps: 1
+populations:
+  - name: sensor
+    count: 1
+    areas: [A]
+    transitions:
+      BROKEN: {HEALTHY: 1}
+`,
+		"unknown target status": `
+steps: 1
+populations:
+  - name: sensor
+    count: 1
+    areas: [A]
+    transitions:
+      HEALTHY: {BROKEN: 1}
+`,
+		"unspecified status": `
+steps: 1
+populations:
+  - name: sensor
+    count: 1
+    areas: [A]
+    transitions:
+      HEALTHY: {UNSPECIFIED: 1}
+`,
+		"negative weight": `
+steps: 1
+populations:
+  - name: sensor
+    count: 1
+    areas: [A]
+    transitions:
+      HEALTHY: {HEALTHY: 2, WARNING: -1}
+`,
+		"no positive weight": `
+steps: 1
+populations:
+  - name: sensor
+    count: 1
+    areas: [A]
+    transitions:
+      HEALTHY: {HEALTHY: 0, WARNING: 0}
+`,
+		"empty transition row": `
+steps: 1
+populations:
+  - name: sensor
+    count: 1
+    areas: [A]
+    transitions:
+      HEALTHY: {}
+`,
+		"invalid event transitions": `
+steps: 1
+populations:
+  - name: sensor
+    count: 1
+    areas: [A]
+timeline:
+  - name: outage
+    transitions:
+      HEALTHY: {CRITICAL: -5}
+`,
+		"event with status and transitions": `
+steps: 1
+populations:
+  - name: sensor
+    count: 1
+    areas: [A]
+timeline:
+  - name: outage
+    status: CRITICAL
+    transitions:
+      HEALTHY: {CRITICAL: 1}
+`,
+	}
+
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Load(writeScenario(t, body)); !errors.Is(err, ErrInvalidScenario) {
+				t.Fatalf("Load error = %v, want %v", err, ErrInvalidScenario)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsMalformedYAML(t *testing.T) {
+	path := writeScenario(t, "steps: 1\npopulations:\n  - name: sensor\n    transitions: [HEALTHY]\n")
+
+	if _, err := Load(path); err == nil {
+		t.Fatalf("Load succeeded, want a parse error for a transition table that is not a map")
+	}
+}
@@ -0,0 +1,150 @@
+package instrumentation
+
+import (
+	"net/http"
+
+	"github.com/charmingruby/devicio/lib/observability"
+	"github.com/charmingruby/devicio/lib/observability/metric"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	Meter observability.Meter
+)
+
+func NewMeter() {
+	Meter = metric.NewPrometheusMeter()
+
+	Meter.NewCounter(observability.CounterInput{
+		Name:      "messages_published",
+		Help:      "Total number of routine messages published",
+		Namespace: "devicio_sim",
+	})
+
+	Meter.NewCounterList(observability.CounterListInput{
+		CounterInput: observability.CounterInput{
+			Name:      "errors",
+			Help:      "Total number of errors by type",
+			Namespace: "devicio_sim",
+		},
+		LabelNames: []string{"error_type"},
+	})
+
+	Meter.NewHistogram(observability.HistogramInput{
+		Name:      "publish_latency",
+		Help:      "Time taken to publish a routine message in seconds",
+		Namespace: "devicio_sim",
+	})
+
+	Meter.NewGauge(observability.GaugeInput{
+		Name:      "target_rate",
+		Help:      "Target publish rate in messages per second",
+		Namespace: "devicio_sim",
+	})
+
+	Meter.NewGauge(observability.GaugeInput{
+		Name:      "achieved_throughput",
+		Help:      "Publish rate achieved over the last report interval in messages per second",
+		Namespace: "devicio_sim",
+	})
+
+	Meter.NewGauge(observability.GaugeInput{
+		Name:      "publish_latency_p50",
+		Help:      "Median publish latency over the last report interval in seconds",
+		Namespace: "devicio_sim",
+	})
+
+	Meter.NewGauge(observability.GaugeInput{
+		Name:      "publish_latency_p95",
+		Help:      "95th percentile publish latency over the last report interval in seconds",
+		Namespace: "devicio_sim",
+	})
+
+	Meter.NewGauge(observability.GaugeInput{
+		Name:      "publish_latency_p99",
+		Help:      "99th percentile publish latency over the last report interval in seconds",
+		Namespace: "devicio_sim",
+	})
+}
+
+func RunMetricsServer(port string) error {
+	http.Handle("/metrics", promhttp.Handler())
+	if err := http.ListenAndServe(":"+port, nil); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func MessagesPublishedCounterMetric() (prometheus.Counter, error) {
+	metric, err := Meter.GetMetric("messages_published", observability.CounterMetricType)
+	if err != nil {
+		return nil, err
+	}
+
+	return metric.(prometheus.Counter), nil
+}
+
+func ErrorsCounterListMetric() (*prometheus.CounterVec, error) {
+	metric, err := Meter.GetMetric("errors", observability.CounterListMetricType)
+	if err != nil {
+		return nil, err
+	}
+
+	return metric.(*prometheus.CounterVec), nil
+}
+
+func PublishLatencyHistogramMetric() (prometheus.Histogram, error) {
+	metric, err := Meter.GetMetric("publish_latency", observability.HistogramMetricType)
+	if err != nil {
+		return nil, err
+	}
+
+	return metric.(prometheus.Histogram), nil
+}
+
+func TargetRateGaugeMetric() (prometheus.Gauge, error) {
+	metric, err := Meter.GetMetric("target_rate", observability.GaugeMetricType)
+	if err != nil {
+		return nil, err
+	}
+
+	return metric.(prometheus.Gauge), nil
+}
+
+func AchievedThroughputGaugeMetric() (prometheus.Gauge, error) {
+	metric, err := Meter.GetMetric("achieved_throughput", observability.GaugeMetricType)
+	if err != nil {
+		return nil, err
+	}
+
+	return metric.(prometheus.Gauge), nil
+}
+
+func PublishLatencyP50GaugeMetric() (prometheus.Gauge, error) {
+	metric, err := Meter.GetMetric("publish_latency_p50", observability.GaugeMetricType)
+	if err != nil {
+		return nil, err
+	}
+
+	return metric.(prometheus.Gauge), nil
+}
+
+func PublishLatencyP95GaugeMetric() (prometheus.Gauge, error) {
+	metric, err := Meter.GetMetric("publish_latency_p95", observability.GaugeMetricType)
+	if err != nil {
+		return nil, err
+	}
+
+	return metric.(prometheus.Gauge), nil
+}
+
+func PublishLatencyP99GaugeMetric() (prometheus.Gauge, error) {
+	metric, err := Meter.GetMetric("publish_latency_p99", observability.GaugeMetricType)
+	if err != nil {
+		return nil, err
+	}
+
+	return metric.(prometheus.Gauge), nil
+}
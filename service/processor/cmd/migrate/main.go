@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/charmingruby/devicio/lib/database"
+	"github.com/charmingruby/devicio/service/processor/config"
+	"github.com/charmingruby/devicio/service/processor/db/migration"
+	"github.com/charmingruby/devicio/service/processor/pkg/instrumentation"
+)
+
+const usage = "usage: migrate <up|down [steps]|status>"
+
+func main() {
+	instrumentation.NewLogger("")
+
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, _, err := config.New()
+	if err != nil {
+		instrumentation.Logger.Error("Failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+
+	db, err := database.NewPostgres(database.PostgresConnectionInput{
+		User:         cfg.Custom.DatabaseUser,
+		Password:     cfg.Custom.DatabasePassword,
+		Host:         cfg.Custom.DatabaseHost,
+		DatabaseName: cfg.Custom.DatabaseName,
+		SSL:          cfg.Custom.DatabaseSSL,
+	})
+	if err != nil {
+		instrumentation.Logger.Error("Failed to establish Postgres connection", "error", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	migrator, err := database.NewMigrator(db, instrumentation.Logger, migration.FS)
+	if err != nil {
+		instrumentation.Logger.Error("Failed to load migrations", "error", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		steps := 1
+
+		if len(os.Args) > 2 {
+			steps, err = strconv.Atoi(os.Args[2])
+			if err != nil || steps < 1 {
+				fmt.Fprintln(os.Stderr, usage)
+				os.Exit(2)
+			}
+		}
+
+		err = migrator.Down(ctx, steps)
+	case "status":
+		var statuses []database.MigrationStatus
+
+		statuses, err = migrator.Status(ctx)
+
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+
+			fmt.Printf("%06d  %-40s  %s\n", s.Version, s.Name, state)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		instrumentation.Logger.Error("Failed to run migrations", "command", os.Args[1], "error", err)
+		os.Exit(1)
+	}
+}
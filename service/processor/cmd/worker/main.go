@@ -2,17 +2,30 @@ package main
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/charmingruby/devicio/lib/core/random"
 	"github.com/charmingruby/devicio/lib/database"
+	"github.com/charmingruby/devicio/lib/messaging"
+	"github.com/charmingruby/devicio/lib/messaging/kafka"
+	"github.com/charmingruby/devicio/lib/messaging/memory"
+	"github.com/charmingruby/devicio/lib/messaging/nats"
 	"github.com/charmingruby/devicio/lib/messaging/rabbitmq"
+	"github.com/charmingruby/devicio/lib/messaging/transport"
 	"github.com/charmingruby/devicio/lib/observability"
+	"github.com/charmingruby/devicio/lib/resilience"
 	"github.com/charmingruby/devicio/service/processor/config"
+	"github.com/charmingruby/devicio/service/processor/db/migration"
+	"github.com/charmingruby/devicio/service/processor/internal/alert"
 	"github.com/charmingruby/devicio/service/processor/internal/device"
 	"github.com/charmingruby/devicio/service/processor/internal/device/client"
 	"github.com/charmingruby/devicio/service/processor/internal/device/postgres"
+	"github.com/charmingruby/devicio/service/processor/internal/device/rest"
 	"github.com/charmingruby/devicio/service/processor/pkg/instrumentation"
 	"github.com/jmoiron/sqlx"
 )
@@ -55,18 +68,84 @@ func main() {
 
 	instrumentation.Logger.Info("Tracing system initialized successfully")
 
-	instrumentation.Logger.Info("Establishing RabbitMQ connection")
+	instrumentation.Logger.Info("Establishing messaging connection", "backend", cfg.Custom.MessagingBackend)
+
+	queue, err := transport.New(instrumentation.Logger, instrumentation.Tracer, &transport.Config{
+		Backend:     cfg.Custom.MessagingBackend,
+		ServiceName: cfg.ServiceName,
+		RabbitMQ: rabbitmq.Config{
+			URL:                 cfg.Custom.RabbitMQURL,
+			QueueName:           cfg.Custom.RabbitMQQueueName,
+			MaxDeliveryAttempts: cfg.Custom.MessagingMaxDeliveryAttempts,
+			RetryBaseDelay:      cfg.Custom.MessagingRetryBaseDelay,
+			RetryMaxDelay:       cfg.Custom.MessagingRetryMaxDelay,
+			PrefetchCount:       cfg.Custom.MessagingPrefetchCount,
+			Concurrency:         cfg.Custom.MessagingConcurrency,
+			DrainTimeout:        cfg.Custom.MessagingDrainTimeout,
+		},
+		NATS: nats.Config{
+			URL:                 cfg.Custom.NATSURL,
+			Stream:              cfg.Custom.NATSStream,
+			Subject:             cfg.Custom.NATSSubject,
+			Durable:             cfg.Custom.NATSDurable,
+			MaxDeliveryAttempts: cfg.Custom.MessagingMaxDeliveryAttempts,
+			RetryBaseDelay:      cfg.Custom.MessagingRetryBaseDelay,
+			RetryMaxDelay:       cfg.Custom.MessagingRetryMaxDelay,
+			PrefetchCount:       cfg.Custom.MessagingPrefetchCount,
+			Concurrency:         cfg.Custom.MessagingConcurrency,
+			DrainTimeout:        cfg.Custom.MessagingDrainTimeout,
+		},
+		Kafka: kafka.Config{
+			Brokers:             cfg.Custom.KafkaBrokers,
+			Topic:               cfg.Custom.KafkaTopic,
+			GroupID:             cfg.Custom.KafkaGroupID,
+			MaxDeliveryAttempts: cfg.Custom.MessagingMaxDeliveryAttempts,
+			RetryBaseDelay:      cfg.Custom.MessagingRetryBaseDelay,
+			RetryMaxDelay:       cfg.Custom.MessagingRetryMaxDelay,
+			Concurrency:         cfg.Custom.MessagingConcurrency,
+			DrainTimeout:        cfg.Custom.MessagingDrainTimeout,
+		},
+		Memory: memory.Config{
+			DeliveryDelay:       cfg.Custom.MemoryDeliveryDelay,
+			MaxDeliveryAttempts: cfg.Custom.MessagingMaxDeliveryAttempts,
+			RetryBaseDelay:      cfg.Custom.MessagingRetryBaseDelay,
+			RetryMaxDelay:       cfg.Custom.MessagingRetryMaxDelay,
+			Concurrency:         cfg.Custom.MessagingConcurrency,
+			DrainTimeout:        cfg.Custom.MessagingDrainTimeout,
+		},
+	})
+	if err != nil {
+		instrumentation.Logger.Error("Failed to establish messaging connection", "error", err)
+		os.Exit(1)
+	}
 
-	queue, err := rabbitmq.New(instrumentation.Logger, instrumentation.Tracer, &rabbitmq.Config{
-		URL:       cfg.Custom.RabbitMQURL,
-		QueueName: cfg.Custom.RabbitMQQueueName,
+	instrumentation.Logger.Info("Messaging connection established successfully")
+
+	instrumentation.Logger.Info("Establishing events publisher connection", "backend", cfg.Custom.MessagingBackend)
+
+	events, err := transport.New(instrumentation.Logger, instrumentation.Tracer, &transport.Config{
+		Backend:     cfg.Custom.MessagingBackend,
+		ServiceName: cfg.ServiceName,
+		RabbitMQ: rabbitmq.Config{
+			URL:      cfg.Custom.RabbitMQURL,
+			Exchange: cfg.Custom.RabbitMQEventsExchange,
+		},
+		NATS: nats.Config{
+			URL:     cfg.Custom.NATSURL,
+			Stream:  cfg.Custom.NATSEventsStream,
+			Subject: cfg.Custom.NATSEventsSubject,
+		},
+		Kafka: kafka.Config{
+			Brokers: cfg.Custom.KafkaBrokers,
+			Topic:   cfg.Custom.KafkaEventsTopic,
+		},
 	})
 	if err != nil {
-		instrumentation.Logger.Error("Failed to establish RabbitMQ connection", "error", err)
+		instrumentation.Logger.Error("Failed to establish events publisher connection", "error", err)
 		os.Exit(1)
 	}
 
-	instrumentation.Logger.Info("RabbitMQ connection established successfully")
+	instrumentation.Logger.Info("Events publisher connection established successfully")
 
 	instrumentation.Logger.Info("Establishing Postgres connection")
 
@@ -84,26 +163,152 @@ func main() {
 
 	instrumentation.Logger.Info("Postgres connection established successfully")
 
-	repo, err := postgres.NewRoutineRepository(db)
+	if cfg.Custom.DatabaseAutoMigrate {
+		instrumentation.Logger.Info("Applying database migrations")
+
+		migrator, err := database.NewMigrator(db, instrumentation.Logger, migration.FS)
+		if err != nil {
+			instrumentation.Logger.Error("Failed to load database migrations", "error", err)
+			os.Exit(1)
+		}
+
+		if err := migrator.Up(context.Background()); err != nil {
+			instrumentation.Logger.Error("Failed to apply database migrations", "error", err)
+			os.Exit(1)
+		}
+
+		instrumentation.Logger.Info("Database migrations applied successfully")
+	}
+
+	var (
+		repo      device.RoutineRepository
+		batchRepo *postgres.BatchRoutineRepository
+	)
+
+	if cfg.Custom.DatabaseBatchSize > 1 {
+		// Handlers block until their routine is flushed, so a batch never
+		// holds more routines than there are concurrent handlers.
+		if concurrency := cfg.Custom.MessagingConcurrency; concurrency > 0 && cfg.Custom.DatabaseBatchSize > concurrency {
+			instrumentation.Logger.Warn("Database batch size exceeds messaging concurrency, capping it",
+				"size", cfg.Custom.DatabaseBatchSize,
+				"concurrency", concurrency,
+			)
+
+			cfg.Custom.DatabaseBatchSize = concurrency
+		}
+
+		instrumentation.Logger.Info("Batching routine inserts",
+			"size", cfg.Custom.DatabaseBatchSize,
+			"flushInterval", cfg.Custom.DatabaseBatchFlushInterval,
+		)
+
+		batchRepo, err = postgres.NewBatchRoutineRepository(db, postgres.BatchConfig{
+			Size:          cfg.Custom.DatabaseBatchSize,
+			FlushInterval: cfg.Custom.DatabaseBatchFlushInterval,
+		})
+		repo = batchRepo
+	} else {
+		repo, err = postgres.NewRoutineRepository(db)
+	}
 	if err != nil {
 		instrumentation.Logger.Error("Failed to create routine repository", "error", err)
 		os.Exit(1)
 	}
 
-	externalAPI := client.NewUnstableAPI()
+	deviceRepo, err := postgres.NewDeviceRepository(db)
+	if err != nil {
+		instrumentation.Logger.Error("Failed to create device repository", "error", err)
+		os.Exit(1)
+	}
+
+	rng := random.New(cfg.Custom.Seed)
+
+	instrumentation.Logger.Info("Creating external API client", "mode", cfg.Custom.ExternalAPIMode, "seed", rng.Seed())
+
+	api, err := client.New(client.Config{
+		Mode: cfg.Custom.ExternalAPIMode,
+		Rand: rng,
+		HTTP: client.HTTPConfig{
+			BaseURL: cfg.Custom.ExternalAPIURL,
+			Path:    cfg.Custom.ExternalAPIPath,
+			Token:   cfg.Custom.ExternalAPIToken,
+		},
+	})
+	if err != nil {
+		instrumentation.Logger.Error("Failed to create external API client", "error", err)
+		os.Exit(1)
+	}
+
+	externalAPI := client.NewResilientAPI(api, resilience.Config{
+		Timeout: cfg.Custom.ExternalAPITimeout,
+		Retry: resilience.RetryConfig{
+			MaxAttempts: cfg.Custom.ExternalAPIMaxAttempts,
+			BaseDelay:   cfg.Custom.ExternalAPIRetryBaseDelay,
+			MaxDelay:    cfg.Custom.ExternalAPIRetryMaxDelay,
+		},
+		Breaker: resilience.BreakerConfig{
+			FailureThreshold: cfg.Custom.ExternalAPIFailureThreshold,
+			OpenTimeout:      cfg.Custom.ExternalAPIOpenTimeout,
+		},
+		Bulkhead: resilience.BulkheadConfig{
+			MaxConcurrent: cfg.Custom.ExternalAPIMaxConcurrent,
+		},
+	})
+
+	var (
+		rules []alert.Rule
+		sinks []alert.Sink
+	)
+
+	if cfg.Custom.AlertRulesFile != "" {
+		instrumentation.Logger.Info("Loading alert rules", "file", cfg.Custom.AlertRulesFile)
+
+		alertCfg, err := alert.LoadConfig(cfg.Custom.AlertRulesFile)
+		if err != nil {
+			instrumentation.Logger.Error("Failed to load alert rules", "error", err)
+			os.Exit(1)
+		}
+
+		sinks, err = alert.NewSinks(alertCfg.Sinks, events)
+		if err != nil {
+			instrumentation.Logger.Error("Failed to create alert sinks", "error", err)
+			os.Exit(1)
+		}
+
+		rules = alertCfg.Rules
+
+		instrumentation.Logger.Info("Alert rules loaded successfully", "rules", len(rules), "sinks", len(sinks))
+	}
+
+	alerts := alert.NewEngine(alert.EngineConfig{
+		EvaluationInterval: cfg.Custom.AlertEvaluationInterval,
+	}, rules, sinks)
 
-	svc := device.NewService(queue, repo, externalAPI)
+	svc := device.NewService(queue, events, repo, deviceRepo, externalAPI, alerts)
 
-	instrumentation.Logger.Info("Subscribing to RabbitMQ queue", "queue", cfg.Custom.RabbitMQQueueName)
+	heartbeat := device.NewHeartbeatMonitor(device.HeartbeatConfig{
+		Interval:       cfg.Custom.HeartbeatInterval,
+		StaleAfter:     cfg.Custom.HeartbeatStaleAfter,
+		AreaStaleAfter: cfg.Custom.HeartbeatAreaStaleAfter,
+	}, deviceRepo, events)
+
+	instrumentation.Logger.Info("Heartbeat monitor started", "interval", cfg.Custom.HeartbeatInterval, "staleAfter", cfg.Custom.HeartbeatStaleAfter)
+
+	instrumentation.Logger.Info("Subscribing to messaging queue", "backend", cfg.Custom.MessagingBackend)
+
+	ctx, stopConsuming := context.WithCancel(context.Background())
+	consumerStopped := make(chan struct{})
 
 	go func() {
-		if err := queue.Subscribe(context.Background(), svc.ProcessRoutine); err != nil {
-			instrumentation.Logger.Error("Failed to subscribe to RabbitMQ queue", "error", err)
+		defer close(consumerStopped)
+
+		if err := queue.Subscribe(ctx, svc.ProcessRoutine); err != nil {
+			instrumentation.Logger.Error("Failed to subscribe to messaging queue", "error", err)
 			os.Exit(1)
 		}
 	}()
 
-	instrumentation.Logger.Info("Subscribed to RabbitMQ queue successfully")
+	instrumentation.Logger.Info("Subscribed to messaging queue successfully")
 
 	go func() {
 		if err := instrumentation.RunMetricsServer(cfg.Custom.MetricsPort); err != nil {
@@ -114,21 +319,87 @@ func main() {
 
 	instrumentation.Logger.Info("Prometheus metrics server started on :2112")
 
-	gracefulShutdown(queue, db)
+	apiServer := rest.NewServer(cfg.Custom.APIPort, rest.NewHandler(svc))
+
+	go func() {
+		if err := apiServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			instrumentation.Logger.Error("Failed to start routines API server", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	instrumentation.Logger.Info("Routines API server started", "port", cfg.Custom.APIPort)
+
+	gracefulShutdown(stopConsuming, consumerStopped, apiServer, cfg.Custom.APIShutdownTimeout, queue, events, alerts, heartbeat, batchRepo, db)
 }
 
-func gracefulShutdown(queue *rabbitmq.Client, db *sqlx.DB) {
+func gracefulShutdown(
+	stopConsuming context.CancelFunc,
+	consumerStopped <-chan struct{},
+	apiServer *http.Server,
+	apiShutdownTimeout time.Duration,
+	queue messaging.Queue,
+	events messaging.Queue,
+	alerts *alert.Engine,
+	heartbeat *device.HeartbeatMonitor,
+	batchRepo *postgres.BatchRoutineRepository,
+	db *sqlx.DB,
+) {
 	stopChan := make(chan os.Signal, 1)
 	signal.Notify(stopChan, os.Interrupt, syscall.SIGTERM)
 
 	<-stopChan
 	instrumentation.Logger.Info("Shutting down gracefully")
 
-	instrumentation.Logger.Info("Closing RabbitMQ connection")
+	instrumentation.Logger.Info("Stopping routines API server")
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiShutdownTimeout)
+	defer cancel()
+
+	if err := apiServer.Shutdown(ctx); err != nil {
+		instrumentation.Logger.Error("Failed to stop routines API server", "error", err)
+	}
+
+	instrumentation.Logger.Info("Routines API server stopped successfully")
+
+	instrumentation.Logger.Info("Stopping consumer and draining in-flight messages")
+
+	stopConsuming()
+	<-consumerStopped
+
+	instrumentation.Logger.Info("Consumer stopped successfully")
+
+	instrumentation.Logger.Info("Stopping heartbeat monitor")
+
+	heartbeat.Close()
+
+	instrumentation.Logger.Info("Heartbeat monitor stopped successfully")
+
+	instrumentation.Logger.Info("Stopping alerts engine")
+
+	alerts.Close()
+
+	instrumentation.Logger.Info("Alerts engine stopped successfully")
+
+	instrumentation.Logger.Info("Closing messaging connection")
 
 	queue.Close()
 
-	instrumentation.Logger.Info("RabbitMQ connection closed successfully")
+	instrumentation.Logger.Info("Messaging connection closed successfully")
+
+	instrumentation.Logger.Info("Closing events publisher connection")
+
+	events.Close()
+
+	instrumentation.Logger.Info("Events publisher connection closed successfully")
+
+	if batchRepo != nil {
+		instrumentation.Logger.Info("Flushing buffered routines")
+
+		batchRepo.Close()
+
+		instrumentation.Logger.Info("Buffered routines flushed successfully")
+	}
 
 	instrumentation.Logger.Info("Closing Postgres connection")
 
@@ -1,18 +1,92 @@
 package config
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/charmingruby/devicio/lib/config"
 )
 
 type CustomConfig struct {
-	RabbitMQURL       string `env:"RABBITMQ_URL"`
-	RabbitMQQueueName string `env:"RABBITMQ_QUEUE_NAME"`
-	DatabaseUser      string `env:"DATABASE_USER,required"`
-	DatabasePassword  string `env:"DATABASE_PASSWORD,required"`
-	DatabaseHost      string `env:"DATABASE_HOST,required"`
-	DatabaseName      string `env:"DATABASE_NAME,required"`
-	DatabaseSSL       string `env:"DATABASE_SSL,required"`
-	MetricsPort       string `env:"METRICS_PORT,required"`
+	MessagingBackend             string        `env:"MESSAGING_BACKEND" envDefault:"rabbitmq"`
+	MessagingMaxDeliveryAttempts int           `env:"MESSAGING_MAX_DELIVERY_ATTEMPTS" envDefault:"5"`
+	MessagingRetryBaseDelay      time.Duration `env:"MESSAGING_RETRY_BASE_DELAY" envDefault:"1s"`
+	MessagingRetryMaxDelay       time.Duration `env:"MESSAGING_RETRY_MAX_DELAY" envDefault:"1m"`
+	MessagingPrefetchCount       int           `env:"MESSAGING_PREFETCH_COUNT" envDefault:"20"`
+	MessagingConcurrency         int           `env:"MESSAGING_CONCURRENCY" envDefault:"10"`
+	MessagingDrainTimeout        time.Duration `env:"MESSAGING_DRAIN_TIMEOUT" envDefault:"30s"`
+	RabbitMQURL                  string        `env:"RABBITMQ_URL"`
+	RabbitMQQueueName            string        `env:"RABBITMQ_QUEUE_NAME"`
+	RabbitMQEventsExchange       string        `env:"RABBITMQ_EVENTS_EXCHANGE" envDefault:"device.status"`
+	NATSURL                      string        `env:"NATS_URL"`
+	NATSStream                   string        `env:"NATS_STREAM"`
+	NATSSubject                  string        `env:"NATS_SUBJECT"`
+	NATSDurable                  string        `env:"NATS_DURABLE"`
+	NATSEventsStream             string        `env:"NATS_EVENTS_STREAM" envDefault:"DEVICE_STATUS"`
+	NATSEventsSubject            string        `env:"NATS_EVENTS_SUBJECT" envDefault:"devices.status"`
+	KafkaBrokers                 []string      `env:"KAFKA_BROKERS" envSeparator:","`
+	KafkaTopic                   string        `env:"KAFKA_TOPIC"`
+	KafkaGroupID                 string        `env:"KAFKA_GROUP_ID"`
+	KafkaEventsTopic             string        `env:"KAFKA_EVENTS_TOPIC" envDefault:"device-status"`
+	MemoryDeliveryDelay          time.Duration `env:"MEMORY_DELIVERY_DELAY"`
+	DatabaseUser                 string        `env:"DATABASE_USER,required"`
+	DatabasePassword             string        `env:"DATABASE_PASSWORD,required"`
+	DatabaseHost                 string        `env:"DATABASE_HOST,required"`
+	DatabaseName                 string        `env:"DATABASE_NAME,required"`
+	DatabaseSSL                  string        `env:"DATABASE_SSL,required"`
+	DatabaseAutoMigrate          bool          `env:"DATABASE_AUTO_MIGRATE" envDefault:"false"`
+	DatabaseBatchSize            int           `env:"DATABASE_BATCH_SIZE" envDefault:"1"`
+	DatabaseBatchFlushInterval   time.Duration `env:"DATABASE_BATCH_FLUSH_INTERVAL" envDefault:"50ms"`
+	ExternalAPIMode              string        `env:"EXTERNAL_API_MODE" envDefault:"chaos"`
+	ExternalAPIURL               string        `env:"EXTERNAL_API_URL"`
+	ExternalAPIPath              string        `env:"EXTERNAL_API_PATH" envDefault:"/"`
+	ExternalAPIToken             string        `env:"EXTERNAL_API_TOKEN"`
+	ExternalAPITimeout           time.Duration `env:"EXTERNAL_API_TIMEOUT" envDefault:"1s"`
+	ExternalAPIMaxAttempts       int           `env:"EXTERNAL_API_MAX_ATTEMPTS" envDefault:"3"`
+	ExternalAPIRetryBaseDelay    time.Duration `env:"EXTERNAL_API_RETRY_BASE_DELAY" envDefault:"100ms"`
+	ExternalAPIRetryMaxDelay     time.Duration `env:"EXTERNAL_API_RETRY_MAX_DELAY" envDefault:"1s"`
+	ExternalAPIFailureThreshold  int           `env:"EXTERNAL_API_FAILURE_THRESHOLD" envDefault:"5"`
+	ExternalAPIOpenTimeout       time.Duration `env:"EXTERNAL_API_OPEN_TIMEOUT" envDefault:"30s"`
+	ExternalAPIMaxConcurrent     int           `env:"EXTERNAL_API_MAX_CONCURRENT" envDefault:"10"`
+	Seed                         int64         `env:"SEED"`
+	MetricsPort                  string        `env:"METRICS_PORT,required"`
+	AlertRulesFile               string        `env:"ALERT_RULES_FILE"`
+	AlertEvaluationInterval      time.Duration `env:"ALERT_EVALUATION_INTERVAL" envDefault:"30s"`
+	HeartbeatInterval            time.Duration `env:"HEARTBEAT_INTERVAL" envDefault:"30s"`
+	HeartbeatStaleAfter          time.Duration `env:"HEARTBEAT_STALE_AFTER" envDefault:"5m"`
+	HeartbeatAreaStaleAfter      DurationMap   `env:"HEARTBEAT_AREA_STALE_AFTER"`
+	APIPort                      string        `env:"API_PORT" envDefault:"8080"`
+	APIShutdownTimeout           time.Duration `env:"API_SHUTDOWN_TIMEOUT" envDefault:"10s"`
+}
+
+// DurationMap parses comma separated key:duration pairs, as in "A:2m,C:10m".
+type DurationMap map[string]time.Duration
+
+func (m *DurationMap) UnmarshalText(text []byte) error {
+	parsed := make(DurationMap)
+
+	for _, pair := range strings.Split(string(text), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(pair, ":")
+		if !ok {
+			return fmt.Errorf("invalid key:duration pair %q", pair)
+		}
+
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid duration for %q: %w", key, err)
+		}
+
+		parsed[strings.TrimSpace(key)] = d
+	}
+
+	*m = parsed
+
+	return nil
 }
 
 func New() (config.Config[CustomConfig], bool, error) {
@@ -1 +1,3 @@
-DROP TABLE IF EXISTS device_routines;
\ No newline at end of file
+DROP TABLE IF EXISTS device_routine_diagnostics;
+
+DROP TABLE IF EXISTS device_routines;
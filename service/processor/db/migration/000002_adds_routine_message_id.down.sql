@@ -0,0 +1,3 @@
+ALTER TABLE device_routines DROP CONSTRAINT IF EXISTS uq_device_routines_message_id;
+
+ALTER TABLE device_routines DROP COLUMN IF EXISTS message_id;
@@ -0,0 +1,7 @@
+ALTER TABLE device_routines ADD COLUMN IF NOT EXISTS message_id varchar;
+
+UPDATE device_routines SET message_id = id WHERE message_id IS NULL;
+
+ALTER TABLE device_routines ALTER COLUMN message_id SET NOT NULL;
+
+ALTER TABLE device_routines ADD CONSTRAINT uq_device_routines_message_id UNIQUE (message_id);
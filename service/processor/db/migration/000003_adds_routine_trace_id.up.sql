@@ -0,0 +1 @@
+ALTER TABLE device_routines ADD COLUMN IF NOT EXISTS trace_id varchar DEFAULT '' NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE device_routines DROP CONSTRAINT IF EXISTS fk_device;
+
+DROP TABLE IF EXISTS devices;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS devices
+(
+    id varchar PRIMARY KEY NOT NULL,
+    status varchar NOT NULL,
+    area varchar NOT NULL,
+    last_seen_at timestamp NOT NULL,
+    created_at timestamp DEFAULT now() NOT NULL
+);
+
+INSERT INTO devices (id, status, area, last_seen_at, created_at)
+SELECT DISTINCT ON (device_id) device_id, status, area, dispatched_at, created_at
+FROM device_routines
+ORDER BY device_id, dispatched_at DESC
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE device_routines
+    ADD CONSTRAINT fk_device FOREIGN KEY (device_id) REFERENCES devices (id) ON DELETE CASCADE ON UPDATE CASCADE;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS device_status_transitions
+(
+    id varchar PRIMARY KEY NOT NULL,
+    device_id varchar NOT NULL,
+    routine_id varchar NOT NULL,
+    from_status varchar NOT NULL,
+    to_status varchar NOT NULL,
+    kind varchar NOT NULL,
+    area varchar NOT NULL,
+    occurred_at timestamp NOT NULL,
+    created_at timestamp DEFAULT now() NOT NULL,
+    CONSTRAINT fk_device FOREIGN KEY (device_id) REFERENCES devices (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_status_transitions_device_id_occurred_at
+    ON device_status_transitions (device_id, occurred_at DESC);
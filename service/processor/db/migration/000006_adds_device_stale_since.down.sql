@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_devices_last_seen_at;
+
+ALTER TABLE devices DROP COLUMN IF EXISTS stale_since;
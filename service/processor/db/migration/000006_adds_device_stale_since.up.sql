@@ -0,0 +1,3 @@
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS stale_since timestamp;
+
+CREATE INDEX IF NOT EXISTS idx_devices_last_seen_at ON devices (last_seen_at);
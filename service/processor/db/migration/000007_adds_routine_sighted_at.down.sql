@@ -0,0 +1 @@
+ALTER TABLE device_routines DROP COLUMN IF EXISTS sighted_at;
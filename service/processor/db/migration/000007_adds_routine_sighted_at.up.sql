@@ -0,0 +1,3 @@
+ALTER TABLE device_routines ADD COLUMN IF NOT EXISTS sighted_at timestamp;
+
+UPDATE device_routines SET sighted_at = created_at WHERE sighted_at IS NULL;
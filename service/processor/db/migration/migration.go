@@ -0,0 +1,8 @@
+package migration
+
+import "embed"
+
+// FS holds the processor's versioned SQL migrations.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,350 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/core/id"
+	"github.com/charmingruby/devicio/service/processor/pkg/instrumentation"
+)
+
+const (
+	defaultSeverity = "warning"
+
+	defaultEvaluationInterval = 30 * time.Second
+
+	// notificationBuffer bounds alerts waiting for delivery to sinks, so a
+	// slow webhook does not stall routine processing. Firing alerts that find
+	// it full are dropped and raised again by a later evaluation; resolves
+	// wait for room so sinks never miss one.
+	notificationBuffer = 256
+)
+
+type State string
+
+const (
+	StateFiring   State = "firing"
+	StateResolved State = "resolved"
+)
+
+type Alert struct {
+	ID         string
+	Rule       string
+	Severity   string
+	State      State
+	DeviceID   string
+	Area       string
+	Message    string
+	FiredAt    time.Time
+	ResolvedAt time.Time
+}
+
+// Observation is a processed routine as seen by the rules engine.
+type Observation struct {
+	DeviceID  string
+	RoutineID string
+	Status    string
+	Area      string
+}
+
+type alertKey struct {
+	rule     string
+	deviceID string
+}
+
+type EngineConfig struct {
+	// EvaluationInterval is how often time based conditions, such as absence
+	// and expiring threshold windows, are checked. Defaults to 30s.
+	EvaluationInterval time.Duration
+}
+
+func (c *EngineConfig) evaluationInterval() time.Duration {
+	if c.EvaluationInterval <= 0 {
+		return defaultEvaluationInterval
+	}
+
+	return c.EvaluationInterval
+}
+
+// Engine evaluates rules against observed routines and notifies sinks when an
+// alert starts firing and when it resolves. An alert fires once per rule and
+// device until it resolves. State is held in memory, so each processor
+// instance alerts on the routines it consumes and restarts begin afresh.
+type Engine struct {
+	mu       sync.Mutex
+	rules    []Rule
+	sinks    []Sink
+	active   map[alertKey]Alert
+	hits     map[alertKey][]time.Time
+	lastSeen map[string]Observation
+	seenAt   map[string]time.Time
+	now      func() time.Time
+
+	notifications chan Alert
+	done          chan struct{}
+	stopped       sync.WaitGroup
+	closeOnce     sync.Once
+}
+
+func NewEngine(cfg EngineConfig, rules []Rule, sinks []Sink) *Engine {
+	e := &Engine{
+		rules:         rules,
+		sinks:         sinks,
+		active:        make(map[alertKey]Alert),
+		hits:          make(map[alertKey][]time.Time),
+		lastSeen:      make(map[string]Observation),
+		seenAt:        make(map[string]time.Time),
+		now:           time.Now,
+		notifications: make(chan Alert, notificationBuffer),
+		done:          make(chan struct{}),
+	}
+
+	e.stopped.Add(2)
+	go e.dispatch()
+	go e.tick(cfg.evaluationInterval())
+
+	return e
+}
+
+// Evaluate applies every rule to a processed routine.
+func (e *Engine) Evaluate(ctx context.Context, obs Observation) {
+	_, complete := instrumentation.Tracer.Span(ctx, "alert.Engine.Evaluate")
+	defer complete()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+
+	e.lastSeen[obs.DeviceID] = obs
+	e.seenAt[obs.DeviceID] = now
+
+	for _, rule := range e.rules {
+		key := alertKey{rule: rule.Name, deviceID: obs.DeviceID}
+
+		switch rule.Type {
+		case RuleMatch:
+			if rule.matches(obs) {
+				e.fire(rule, obs, now, fmt.Sprintf("device %s reported %s in area %s", obs.DeviceID, obs.Status, obs.Area))
+			} else {
+				e.resolve(key, now)
+			}
+		case RuleThreshold:
+			hits := pruneHits(e.hits[key], now.Add(-rule.Window))
+			if rule.matches(obs) {
+				hits = append(hits, now)
+			}
+			e.storeHits(key, hits)
+
+			if len(hits) >= rule.Count {
+				e.fire(rule, obs, now, fmt.Sprintf("device %s matched %d routines within %s", obs.DeviceID, len(hits), rule.Window))
+			} else {
+				e.resolve(key, now)
+			}
+		case RuleAbsence:
+			e.resolve(key, now)
+		}
+	}
+}
+
+// evaluateTime checks conditions that change with time alone: silent devices
+// and threshold windows that no longer hold enough routines.
+func (e *Engine) evaluateTime() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+
+	for _, rule := range e.rules {
+		switch rule.Type {
+		case RuleThreshold:
+			for key, hits := range e.hits {
+				if key.rule != rule.Name {
+					continue
+				}
+
+				hits = pruneHits(hits, now.Add(-rule.Window))
+				e.storeHits(key, hits)
+
+				if len(hits) < rule.Count {
+					e.resolve(key, now)
+				}
+			}
+		case RuleAbsence:
+			for deviceID, seenAt := range e.seenAt {
+				obs := e.lastSeen[deviceID]
+				if !matchesAny(rule.Areas, obs.Area) || !matchesAny(rule.Devices, deviceID) {
+					continue
+				}
+
+				if silence := now.Sub(seenAt); silence >= rule.After {
+					e.fire(rule, obs, now, fmt.Sprintf("device %s has not reported for %s", deviceID, silence.Truncate(time.Second)))
+				}
+			}
+		}
+	}
+}
+
+func (e *Engine) storeHits(key alertKey, hits []time.Time) {
+	if len(hits) == 0 {
+		delete(e.hits, key)
+		return
+	}
+
+	e.hits[key] = hits
+}
+
+func pruneHits(hits []time.Time, since time.Time) []time.Time {
+	i := 0
+	for i < len(hits) && hits[i].Before(since) {
+		i++
+	}
+
+	return hits[i:]
+}
+
+// fire raises an alert unless one is already active for the rule and device.
+func (e *Engine) fire(rule Rule, obs Observation, now time.Time, message string) {
+	key := alertKey{rule: rule.Name, deviceID: obs.DeviceID}
+	if _, ok := e.active[key]; ok {
+		return
+	}
+
+	severity := rule.Severity
+	if severity == "" {
+		severity = defaultSeverity
+	}
+
+	a := Alert{
+		ID:       id.New(),
+		Rule:     rule.Name,
+		Severity: severity,
+		State:    StateFiring,
+		DeviceID: obs.DeviceID,
+		Area:     obs.Area,
+		Message:  message,
+		FiredAt:  now,
+	}
+
+	// A dropped alert is not marked active, so the next evaluation that
+	// still meets the condition raises it again.
+	if !e.notify(a) {
+		return
+	}
+
+	e.active[key] = a
+}
+
+// resolve clears an active alert and sends its resolve notification.
+func (e *Engine) resolve(key alertKey, now time.Time) {
+	a, ok := e.active[key]
+	if !ok {
+		return
+	}
+
+	delete(e.active, key)
+
+	a.State = StateResolved
+	a.ResolvedAt = now
+	e.notify(a)
+}
+
+// notify queues an alert for the sinks and reports whether it was queued.
+// Firing alerts are dropped when the buffer is full, while resolves block
+// until there is room or the engine is closed.
+func (e *Engine) notify(a Alert) bool {
+	if a.State == StateResolved {
+		select {
+		case e.notifications <- a:
+		case <-e.done:
+			instrumentation.Logger.Warn("Alerts engine closed, dropping alert", "alertId", a.ID, "rule", a.Rule, "state", a.State)
+			e.countDropped()
+			return false
+		}
+	} else {
+		select {
+		case e.notifications <- a:
+		default:
+			instrumentation.Logger.Warn("Alert notification buffer full, dropping alert", "alertId", a.ID, "rule", a.Rule, "state", a.State)
+			e.countDropped()
+			return false
+		}
+	}
+
+	alertsCounterListMetric, err := instrumentation.AlertsCounterListMetric()
+	if err != nil {
+		instrumentation.Logger.Error("Failed to get alerts counter list metric", "error", err)
+	} else {
+		alertsCounterListMetric.WithLabelValues(a.Rule, string(a.State)).Inc()
+	}
+
+	return true
+}
+
+func (e *Engine) countDropped() {
+	alertsDroppedCounterMetric, err := instrumentation.AlertsDroppedCounterMetric()
+	if err != nil {
+		instrumentation.Logger.Error("Failed to get alerts dropped counter metric", "error", err)
+		return
+	}
+
+	alertsDroppedCounterMetric.Inc()
+}
+
+func (e *Engine) dispatch() {
+	defer e.stopped.Done()
+
+	for {
+		select {
+		case a := <-e.notifications:
+			e.send(a)
+		case <-e.done:
+			for {
+				select {
+				case a := <-e.notifications:
+					e.send(a)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (e *Engine) send(a Alert) {
+	ctx, complete := instrumentation.Tracer.Span(context.Background(), "alert.Engine.send")
+	defer complete()
+
+	for _, sink := range e.sinks {
+		if err := sink.Send(ctx, a); err != nil {
+			instrumentation.Logger.Error("Failed to send alert", "alertId", a.ID, "sink", fmt.Sprintf("%T", sink), "error", err)
+		}
+	}
+}
+
+func (e *Engine) tick(interval time.Duration) {
+	defer e.stopped.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.evaluateTime()
+		case <-e.done:
+			return
+		}
+	}
+}
+
+// Close stops time based evaluation and delivers pending notifications.
+func (e *Engine) Close() {
+	e.closeOnce.Do(func() {
+		close(e.done)
+	})
+
+	e.stopped.Wait()
+}
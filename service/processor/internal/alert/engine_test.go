@@ -0,0 +1,287 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/charmingruby/devicio/service/processor/pkg/instrumentation"
+)
+
+type nopTracer struct{}
+
+func (nopTracer) Span(ctx context.Context, _ string) (context.Context, func()) {
+	return ctx, func() {}
+}
+
+func (nopTracer) GetTraceIDFromContext(context.Context) string { return "" }
+
+func (nopTracer) Inject(context.Context, map[string]string) {}
+
+func (nopTracer) Extract(ctx context.Context, _ map[string]string) context.Context {
+	return ctx
+}
+
+func (nopTracer) Close() error { return nil }
+
+func TestMain(m *testing.M) {
+	instrumentation.NewLogger("error")
+	instrumentation.NewMeter()
+	instrumentation.Tracer = nopTracer{}
+
+	os.Exit(m.Run())
+}
+
+// testClock is a manually advanced clock for the engine's now.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func (c *testClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// newTestEngine builds an engine without its dispatch and tick goroutines, so
+// tests drive time based evaluation and read notifications directly.
+func newTestEngine(t *testing.T, buffer int, rules ...Rule) (*Engine, *testClock) {
+	t.Helper()
+
+	clock := &testClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+
+	e := &Engine{
+		rules:         rules,
+		active:        make(map[alertKey]Alert),
+		hits:          make(map[alertKey][]time.Time),
+		lastSeen:      make(map[string]Observation),
+		seenAt:        make(map[string]time.Time),
+		now:           clock.Now,
+		notifications: make(chan Alert, buffer),
+		done:          make(chan struct{}),
+	}
+
+	return e, clock
+}
+
+func drain(e *Engine) []Alert {
+	var alerts []Alert
+
+	for {
+		select {
+		case a := <-e.notifications:
+			alerts = append(alerts, a)
+		default:
+			return alerts
+		}
+	}
+}
+
+func expectNotifications(t *testing.T, e *Engine, want ...State) []Alert {
+	t.Helper()
+
+	got := drain(e)
+	if len(got) != len(want) {
+		t.Fatalf("notifications = %v, want states %v", got, want)
+	}
+
+	for i, a := range got {
+		if a.State != want[i] {
+			t.Fatalf("notification %d state = %s, want %s", i, a.State, want[i])
+		}
+	}
+
+	return got
+}
+
+func observe(e *Engine, deviceID, status string) {
+	e.Evaluate(context.Background(), Observation{DeviceID: deviceID, Status: status, Area: "A"})
+}
+
+func TestMatchRuleFiresOncePerDevice(t *testing.T) {
+	e, _ := newTestEngine(t, notificationBuffer, Rule{Name: "critical", Type: RuleMatch, Statuses: []string{"CRITICAL"}})
+
+	observe(e, "device-1", "CRITICAL")
+	observe(e, "device-1", "CRITICAL")
+	observe(e, "device-2", "CRITICAL")
+
+	alerts := expectNotifications(t, e, StateFiring, StateFiring)
+	if alerts[0].DeviceID != "device-1" || alerts[1].DeviceID != "device-2" {
+		t.Fatalf("fired for devices %s and %s, want device-1 and device-2", alerts[0].DeviceID, alerts[1].DeviceID)
+	}
+}
+
+func TestMatchRuleResolvesWhenConditionClears(t *testing.T) {
+	e, _ := newTestEngine(t, notificationBuffer, Rule{Name: "critical", Type: RuleMatch, Statuses: []string{"CRITICAL"}})
+
+	observe(e, "device-1", "CRITICAL")
+	fired := expectNotifications(t, e, StateFiring)
+
+	observe(e, "device-1", "HEALTHY")
+	resolved := expectNotifications(t, e, StateResolved)
+
+	if resolved[0].ID != fired[0].ID {
+		t.Fatalf("resolved alert %s, want %s", resolved[0].ID, fired[0].ID)
+	}
+
+	// a device that never fired has nothing to resolve
+	observe(e, "device-2", "HEALTHY")
+	expectNotifications(t, e)
+}
+
+func TestThresholdRuleFiresWithinWindowAndResolvesAsHitsExpire(t *testing.T) {
+	e, clock := newTestEngine(t, notificationBuffer, Rule{
+		Name:     "repeated-errors",
+		Type:     RuleThreshold,
+		Statuses: []string{"ERROR"},
+		Count:    3,
+		Window:   time.Minute,
+	})
+
+	observe(e, "device-1", "ERROR")
+	clock.Advance(20 * time.Second)
+	observe(e, "device-1", "HEALTHY")
+	observe(e, "device-1", "ERROR")
+	expectNotifications(t, e)
+
+	clock.Advance(20 * time.Second)
+	observe(e, "device-1", "ERROR")
+	expectNotifications(t, e, StateFiring)
+
+	// the first hit leaves the window, dropping the device below the count
+	clock.Advance(30 * time.Second)
+	e.evaluateTime()
+	expectNotifications(t, e, StateResolved)
+}
+
+func TestThresholdRuleIgnoresHitsOutsideWindow(t *testing.T) {
+	e, clock := newTestEngine(t, notificationBuffer, Rule{Name: "repeated-errors", Type: RuleThreshold, Count: 2, Window: time.Minute})
+
+	observe(e, "device-1", "ERROR")
+	clock.Advance(2 * time.Minute)
+	observe(e, "device-1", "ERROR")
+
+	expectNotifications(t, e)
+}
+
+func TestAbsenceRuleFiresForSilentDevicesAndResolvesOnReport(t *testing.T) {
+	e, clock := newTestEngine(t, notificationBuffer, Rule{Name: "silent", Type: RuleAbsence, After: 10 * time.Minute, Areas: []string{"A"}})
+
+	observe(e, "device-1", "HEALTHY")
+	e.Evaluate(context.Background(), Observation{DeviceID: "device-2", Status: "HEALTHY", Area: "B"})
+
+	clock.Advance(5 * time.Minute)
+	e.evaluateTime()
+	expectNotifications(t, e)
+
+	clock.Advance(5 * time.Minute)
+	e.evaluateTime()
+	e.evaluateTime()
+
+	alerts := expectNotifications(t, e, StateFiring)
+	if alerts[0].DeviceID != "device-1" {
+		t.Fatalf("fired for %s, want device-1 outside the filtered area to be ignored", alerts[0].DeviceID)
+	}
+
+	observe(e, "device-1", "HEALTHY")
+	expectNotifications(t, e, StateResolved)
+}
+
+func TestFullBufferDropsFiringAlertsUntilThereIsRoom(t *testing.T) {
+	e, _ := newTestEngine(t, 1,
+		Rule{Name: "critical", Type: RuleMatch, Statuses: []string{"CRITICAL"}},
+		Rule{Name: "in-area", Type: RuleMatch, Areas: []string{"A"}},
+	)
+
+	// the second rule's alert finds the buffer full and is dropped
+	observe(e, "device-1", "CRITICAL")
+	alerts := expectNotifications(t, e, StateFiring)
+	if alerts[0].Rule != "critical" {
+		t.Fatalf("queued alert for rule %s, want critical", alerts[0].Rule)
+	}
+
+	// the dropped alert was not recorded as active, so it fires again
+	observe(e, "device-1", "CRITICAL")
+	alerts = expectNotifications(t, e, StateFiring)
+	if alerts[0].Rule != "in-area" {
+		t.Fatalf("queued alert for rule %s, want in-area", alerts[0].Rule)
+	}
+}
+
+func TestResolveWaitsForRoomInFullBuffer(t *testing.T) {
+	e, _ := newTestEngine(t, 1, Rule{Name: "critical", Type: RuleMatch, Statuses: []string{"CRITICAL"}})
+
+	// the firing alert fills the buffer before the condition clears
+	observe(e, "device-1", "CRITICAL")
+
+	evaluated := make(chan struct{})
+	go func() {
+		defer close(evaluated)
+		observe(e, "device-1", "HEALTHY")
+	}()
+
+	select {
+	case <-evaluated:
+		t.Fatalf("evaluation completed without room for the resolve")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	var got []Alert
+	for len(got) < 2 {
+		select {
+		case a := <-e.notifications:
+			got = append(got, a)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("notifications = %v, want the resolve to be delivered", got)
+		}
+	}
+
+	<-evaluated
+
+	if got[0].State != StateFiring || got[1].State != StateResolved {
+		t.Fatalf("notifications = %v, want a firing alert followed by its resolve", got)
+	}
+}
+
+func TestResolveIsDroppedOnceClosed(t *testing.T) {
+	e, _ := newTestEngine(t, 1, Rule{Name: "critical", Type: RuleMatch, Statuses: []string{"CRITICAL"}})
+
+	observe(e, "device-1", "CRITICAL")
+	close(e.done)
+
+	// with no dispatcher left to make room, the resolve must not block
+	observe(e, "device-1", "HEALTHY")
+
+	expectNotifications(t, e, StateFiring)
+}
+
+func TestRuleValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  Rule
+		valid bool
+	}{
+		{name: "match", rule: Rule{Name: "r", Type: RuleMatch}, valid: true},
+		{name: "missing name", rule: Rule{Type: RuleMatch}},
+		{name: "unknown type", rule: Rule{Name: "r", Type: "sometimes"}},
+		{name: "threshold", rule: Rule{Name: "r", Type: RuleThreshold, Count: 2, Window: time.Minute}, valid: true},
+		{name: "threshold without window", rule: Rule{Name: "r", Type: RuleThreshold, Count: 2}},
+		{name: "absence", rule: Rule{Name: "r", Type: RuleAbsence, After: time.Minute, Areas: []string{"A"}}, valid: true},
+		{name: "absence without after", rule: Rule{Name: "r", Type: RuleAbsence}},
+		{name: "absence with statuses", rule: Rule{Name: "r", Type: RuleAbsence, After: time.Minute, Statuses: []string{"ERROR"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.validate()
+
+			if tt.valid && err != nil {
+				t.Fatalf("validate: %v", err)
+			}
+
+			if !tt.valid && !errors.Is(err, ErrInvalidRule) {
+				t.Fatalf("validate error = %v, want %v", err, ErrInvalidRule)
+			}
+		})
+	}
+}
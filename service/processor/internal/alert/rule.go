@@ -0,0 +1,128 @@
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type RuleType string
+
+const (
+	// RuleMatch fires while a device's latest routine matches the rule.
+	RuleMatch RuleType = "match"
+	// RuleThreshold fires when a device sends Count matching routines
+	// within Window.
+	RuleThreshold RuleType = "threshold"
+	// RuleAbsence fires when a device sends no routine for After.
+	RuleAbsence RuleType = "absence"
+)
+
+var ErrInvalidRule = errors.New("invalid rule")
+
+// Rule describes a condition over incoming routines. Statuses, Areas and
+// Devices filter which routines the rule considers; empty filters match
+// everything. Absence rules filter on Areas and Devices only.
+type Rule struct {
+	Name     string   `yaml:"name"`
+	Type     RuleType `yaml:"type"`
+	Severity string   `yaml:"severity"`
+
+	Statuses []string `yaml:"statuses"`
+	Areas    []string `yaml:"areas"`
+	Devices  []string `yaml:"devices"`
+
+	Count  int           `yaml:"count"`
+	Window time.Duration `yaml:"window"`
+	After  time.Duration `yaml:"after"`
+}
+
+func (r Rule) matches(obs Observation) bool {
+	return matchesAny(r.Statuses, obs.Status) &&
+		matchesAny(r.Areas, obs.Area) &&
+		matchesAny(r.Devices, obs.DeviceID)
+}
+
+func matchesAny(values []string, v string) bool {
+	return len(values) == 0 || slices.Contains(values, v)
+}
+
+func (r Rule) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("%w: missing name", ErrInvalidRule)
+	}
+
+	switch r.Type {
+	case RuleMatch:
+	case RuleThreshold:
+		if r.Count < 1 || r.Window <= 0 {
+			return fmt.Errorf("%w: %s: threshold rules need a positive count and window", ErrInvalidRule, r.Name)
+		}
+	case RuleAbsence:
+		if r.After <= 0 {
+			return fmt.Errorf("%w: %s: absence rules need a positive after", ErrInvalidRule, r.Name)
+		}
+
+		// A silent device reports no status to filter on.
+		if len(r.Statuses) > 0 {
+			return fmt.Errorf("%w: %s: absence rules do not filter on statuses", ErrInvalidRule, r.Name)
+		}
+	default:
+		return fmt.Errorf("%w: %s: unknown type %q", ErrInvalidRule, r.Name, r.Type)
+	}
+
+	return nil
+}
+
+type SinkType string
+
+const (
+	SinkLog     SinkType = "log"
+	SinkWebhook SinkType = "webhook"
+	SinkQueue   SinkType = "queue"
+)
+
+type SinkConfig struct {
+	Type    SinkType      `yaml:"type"`
+	URL     string        `yaml:"url"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Config is the declarative rules file. It is read as YAML, so JSON files
+// are accepted as well.
+type Config struct {
+	Rules []Rule       `yaml:"rules"`
+	Sinks []SinkConfig `yaml:"sinks"`
+}
+
+func LoadConfig(path string) (Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read rules file: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to parse rules file: %w", err)
+	}
+
+	names := make(map[string]bool, len(cfg.Rules))
+
+	for _, r := range cfg.Rules {
+		if err := r.validate(); err != nil {
+			return Config{}, err
+		}
+
+		if names[r.Name] {
+			return Config{}, fmt.Errorf("%w: duplicate name %s", ErrInvalidRule, r.Name)
+		}
+
+		names[r.Name] = true
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,174 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/messaging"
+	"github.com/charmingruby/devicio/lib/proto/gen/pb"
+	"github.com/charmingruby/devicio/service/processor/pkg/instrumentation"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const defaultWebhookTimeout = 5 * time.Second
+
+type Sink interface {
+	Send(ctx context.Context, a Alert) error
+}
+
+// NewSinks builds the sinks described by cfgs. Queue sinks publish through
+// queue, which may be nil when none are configured.
+func NewSinks(cfgs []SinkConfig, queue messaging.Queue) ([]Sink, error) {
+	sinks := make([]Sink, 0, len(cfgs))
+
+	for _, cfg := range cfgs {
+		switch cfg.Type {
+		case SinkLog:
+			sinks = append(sinks, LogSink{})
+		case SinkWebhook:
+			if cfg.URL == "" {
+				return nil, errors.New("webhook sink requires a url")
+			}
+
+			sinks = append(sinks, NewWebhookSink(cfg.URL, cfg.Timeout))
+		case SinkQueue:
+			if queue == nil {
+				return nil, errors.New("queue sink requires a messaging queue")
+			}
+
+			sinks = append(sinks, QueueSink{queue: queue})
+		default:
+			return nil, fmt.Errorf("unknown sink type %q", cfg.Type)
+		}
+	}
+
+	return sinks, nil
+}
+
+type LogSink struct{}
+
+func (LogSink) Send(_ context.Context, a Alert) error {
+	args := []any{
+		"alertId", a.ID,
+		"rule", a.Rule,
+		"severity", a.Severity,
+		"deviceId", a.DeviceID,
+		"area", a.Area,
+		"message", a.Message,
+	}
+
+	if a.State == StateResolved {
+		instrumentation.Logger.Info("Alert resolved", args...)
+		return nil
+	}
+
+	instrumentation.Logger.Warn("Alert firing", args...)
+
+	return nil
+}
+
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookSink(url string, timeout time.Duration) *WebhookSink {
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+
+	return &WebhookSink{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+type webhookPayload struct {
+	ID         string     `json:"id"`
+	Rule       string     `json:"rule"`
+	Severity   string     `json:"severity"`
+	State      State      `json:"state"`
+	DeviceID   string     `json:"device_id"`
+	Area       string     `json:"area"`
+	Message    string     `json:"message"`
+	FiredAt    time.Time  `json:"fired_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+func (s *WebhookSink) Send(ctx context.Context, a Alert) error {
+	payload := webhookPayload{
+		ID:       a.ID,
+		Rule:     a.Rule,
+		Severity: a.Severity,
+		State:    a.State,
+		DeviceID: a.DeviceID,
+		Area:     a.Area,
+		Message:  a.Message,
+		FiredAt:  a.FiredAt,
+	}
+
+	if !a.ResolvedAt.IsZero() {
+		payload.ResolvedAt = &a.ResolvedAt
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	carrier := make(map[string]string)
+	instrumentation.Tracer.Inject(ctx, carrier)
+
+	for k, v := range carrier {
+		req.Header.Set(k, v)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("webhook responded with status %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+type QueueSink struct {
+	queue messaging.Queue
+}
+
+func (s QueueSink) Send(ctx context.Context, a Alert) error {
+	msg := &pb.DeviceAlert{
+		Id:       a.ID,
+		Rule:     a.Rule,
+		Severity: a.Severity,
+		State:    string(a.State),
+		DeviceId: a.DeviceID,
+		Area:     a.Area,
+		Message:  a.Message,
+		FiredAt:  timestamppb.New(a.FiredAt),
+	}
+
+	if !a.ResolvedAt.IsZero() {
+		msg.ResolvedAt = timestamppb.New(a.ResolvedAt)
+	}
+
+	_, err := s.queue.Publish(ctx, msg)
+
+	return err
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/charmingruby/devicio/lib/core/random"
+	"github.com/charmingruby/devicio/service/processor/internal/device"
+)
+
+const (
+	ModeChaos = "chaos"
+	ModeHTTP  = "http"
+)
+
+var ErrUnknownMode = errors.New("unknown external api mode")
+
+// Config selects an external API implementation; HTTP is only used in HTTP
+// mode and Rand only in chaos mode.
+type Config struct {
+	Mode string
+	HTTP HTTPConfig
+	// Rand drives the chaos simulator. Defaults to a time seeded source.
+	Rand *random.Rand
+}
+
+// New builds the external API for the configured mode, defaulting to the
+// chaos simulator.
+func New(cfg Config) (device.ExternalAPI, error) {
+	switch cfg.Mode {
+	case "", ModeChaos:
+		rng := cfg.Rand
+		if rng == nil {
+			rng = random.New(0)
+		}
+
+		return NewUnstableAPI(rng), nil
+	case ModeHTTP:
+		if cfg.HTTP.BaseURL == "" {
+			return nil, errors.New("http external api requires a base url")
+		}
+
+		return NewHTTPAPI(cfg.HTTP), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMode, cfg.Mode)
+	}
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/charmingruby/devicio/service/processor/pkg/instrumentation"
+)
+
+const defaultHTTPPath = "/"
+
+type HTTPConfig struct {
+	// BaseURL is the external API address, as in "https://api.example.com".
+	BaseURL string
+	// Path is requested relative to BaseURL. Defaults to "/".
+	Path string
+	// Token is sent as a bearer token when set.
+	Token string
+	// Client performs the requests. Defaults to a client without a timeout,
+	// leaving deadlines to the caller's context.
+	Client *http.Client
+}
+
+func (c *HTTPConfig) url() string {
+	path := c.Path
+	if path == "" {
+		path = defaultHTTPPath
+	}
+
+	return strings.TrimRight(c.BaseURL, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+func (c *HTTPConfig) client() *http.Client {
+	if c.Client == nil {
+		return &http.Client{}
+	}
+
+	return c.Client
+}
+
+// HTTPAPI calls the external API over HTTP, propagating the trace context in
+// the request headers. Connection failures, truncated responses, 429 and 5xx
+// responses map to ErrUnstable; any other non 2xx response maps to
+// ErrUnknown.
+type HTTPAPI struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func NewHTTPAPI(cfg HTTPConfig) *HTTPAPI {
+	return &HTTPAPI{
+		url:    cfg.url(),
+		token:  cfg.Token,
+		client: cfg.client(),
+	}
+}
+
+func (a *HTTPAPI) VolatileCall(ctx context.Context) (context.Context, error) {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "external.HTTPAPI.VolatileCall")
+	defer complete()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, nil)
+	if err != nil {
+		return ctx, err
+	}
+
+	if a.token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	}
+
+	carrier := make(map[string]string)
+	instrumentation.Tracer.Inject(ctx, carrier)
+
+	for k, v := range carrier {
+		req.Header.Set(k, v)
+	}
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx, ctx.Err()
+		}
+
+		return ctx, fmt.Errorf("%w: %v", ErrUnstable, err)
+	}
+	defer res.Body.Close()
+
+	// Drain the body so the connection can be reused. A body cut short
+	// leaves the outcome of the call unknown, like a dropped connection.
+	if _, err := io.Copy(io.Discard, res.Body); err != nil {
+		if ctx.Err() != nil {
+			return ctx, ctx.Err()
+		}
+
+		return ctx, fmt.Errorf("%w: failed to read response body: %v", ErrUnstable, err)
+	}
+
+	switch {
+	case res.StatusCode < http.StatusBadRequest:
+		return ctx, nil
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError:
+		return ctx, fmt.Errorf("%w: status %d", ErrUnstable, res.StatusCode)
+	default:
+		return ctx, fmt.Errorf("%w: status %d", ErrUnknown, res.StatusCode)
+	}
+}
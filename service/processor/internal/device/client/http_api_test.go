@@ -0,0 +1,144 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/charmingruby/devicio/service/processor/pkg/instrumentation"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// stubTracer injects a fixed W3C trace context so requests can be checked
+// for propagation without a tracing backend.
+type stubTracer struct{}
+
+func (stubTracer) Span(ctx context.Context, _ string) (context.Context, func()) {
+	return ctx, func() {}
+}
+
+func (stubTracer) GetTraceIDFromContext(context.Context) string { return "" }
+
+func (stubTracer) Inject(_ context.Context, carrier map[string]string) {
+	carrier["traceparent"] = testTraceParent
+}
+
+func (stubTracer) Extract(ctx context.Context, _ map[string]string) context.Context {
+	return ctx
+}
+
+func (stubTracer) Close() error { return nil }
+
+func TestMain(m *testing.M) {
+	instrumentation.NewLogger("error")
+	instrumentation.Tracer = stubTracer{}
+
+	os.Exit(m.Run())
+}
+
+func TestHTTPAPIVolatileCallSucceeds(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/check" {
+			t.Errorf("request = %s %s, want POST /v1/check", r.Method, r.URL.Path)
+		}
+
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q, want bearer token", got)
+		}
+
+		if got := r.Header.Get("traceparent"); got != testTraceParent {
+			t.Errorf("traceparent = %q, want %q", got, testTraceParent)
+		}
+
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	api := NewHTTPAPI(HTTPConfig{BaseURL: server.URL + "/", Path: "v1/check", Token: "secret"})
+
+	if _, err := api.VolatileCall(context.Background()); err != nil {
+		t.Fatalf("VolatileCall: %v", err)
+	}
+}
+
+func TestHTTPAPIVolatileCallMapsStatusCodes(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{status: http.StatusTooManyRequests, want: ErrUnstable},
+		{status: http.StatusInternalServerError, want: ErrUnstable},
+		{status: http.StatusServiceUnavailable, want: ErrUnstable},
+		{status: http.StatusBadRequest, want: ErrUnknown},
+		{status: http.StatusUnauthorized, want: ErrUnknown},
+		{status: http.StatusNotFound, want: ErrUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			_, err := NewHTTPAPI(HTTPConfig{BaseURL: server.URL}).VolatileCall(context.Background())
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("VolatileCall error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPAPIVolatileCallTimesOut(t *testing.T) {
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	t.Run("context deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err := NewHTTPAPI(HTTPConfig{BaseURL: server.URL}).VolatileCall(ctx)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("VolatileCall error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("client timeout", func(t *testing.T) {
+		api := NewHTTPAPI(HTTPConfig{
+			BaseURL: server.URL,
+			Client:  &http.Client{Timeout: 20 * time.Millisecond},
+		})
+
+		_, err := api.VolatileCall(context.Background())
+		if !errors.Is(err, ErrUnstable) {
+			t.Fatalf("VolatileCall error = %v, want %v", err, ErrUnstable)
+		}
+	})
+}
+
+func TestHTTPAPIVolatileCallRejectsTruncatedBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// declare more bytes than are sent, so the body ends early
+		w.Header().Set("Content-Length", "64")
+		w.Write([]byte(`{"ok":`))
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPAPI(HTTPConfig{BaseURL: server.URL}).VolatileCall(context.Background())
+	if !errors.Is(err, ErrUnstable) {
+		t.Fatalf("VolatileCall error = %v, want %v", err, ErrUnstable)
+	}
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/resilience"
+	"github.com/charmingruby/devicio/service/processor/internal/device"
+	"github.com/charmingruby/devicio/service/processor/pkg/instrumentation"
+)
+
+// ResilientAPI guards the external API with a per-call timeout, retries of
+// transient failures, a circuit breaker and a bulkhead.
+type ResilientAPI struct {
+	api    device.ExternalAPI
+	policy *resilience.Policy
+}
+
+// NewResilientAPI wraps api with the policy described by cfg. Error
+// classification and metric hooks are set here and override those in cfg.
+func NewResilientAPI(api device.ExternalAPI, cfg resilience.Config) *ResilientAPI {
+	cfg.Retry.Retryable = isTransient
+	cfg.Retry.OnRetry = onRetry
+	cfg.Breaker.OnStateChange = onBreakerStateChange
+
+	setBreakerStateMetric(resilience.StateClosed)
+
+	return &ResilientAPI{
+		api:    api,
+		policy: resilience.New(cfg),
+	}
+}
+
+func (a *ResilientAPI) VolatileCall(ctx context.Context) (context.Context, error) {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "external.ResilientAPI.VolatileCall")
+	defer complete()
+
+	// Attempts run under their own timeouts, so the caller keeps this span's
+	// context rather than one that is cancelled once the attempt returns.
+	err := a.policy.Execute(ctx, func(ctx context.Context) error {
+		_, err := a.api.VolatileCall(ctx)
+		return err
+	})
+
+	return ctx, err
+}
+
+// isTransient reports whether a failed call is worth retrying. ErrUnknown is
+// treated as permanent since repeating the call is not expected to help.
+func isTransient(err error) bool {
+	return errors.Is(err, ErrUnstable) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func onRetry(attempt int, err error, delay time.Duration) {
+	instrumentation.Logger.Debug("Retrying external API call", "attempt", attempt, "delay", delay, "error", err)
+
+	retriesCounterMetric, err := instrumentation.ExternalAPIRetriesCounterMetric()
+	if err != nil {
+		instrumentation.Logger.Error("Failed to get external api retries counter metric", "error", err)
+		return
+	}
+
+	retriesCounterMetric.Inc()
+}
+
+func onBreakerStateChange(from, to resilience.BreakerState) {
+	instrumentation.Logger.Warn("External API circuit breaker changed state", "from", from.String(), "to", to.String())
+
+	setBreakerStateMetric(to)
+}
+
+func setBreakerStateMetric(state resilience.BreakerState) {
+	breakerStateGaugeMetric, err := instrumentation.ExternalAPICircuitStateGaugeMetric()
+	if err != nil {
+		instrumentation.Logger.Error("Failed to get external api circuit state gauge metric", "error", err)
+		return
+	}
+
+	breakerStateGaugeMetric.Set(float64(state))
+}
@@ -3,9 +3,10 @@ package client
 import (
 	"context"
 	"errors"
-	"math/rand"
+	"sync/atomic"
 	"time"
 
+	"github.com/charmingruby/devicio/lib/core/random"
 	"github.com/charmingruby/devicio/service/processor/pkg/instrumentation"
 )
 
@@ -30,23 +31,32 @@ var (
 	}
 )
 
+// UnstableAPI is a chaos implementation of the external API that simulates
+// latency and fails at random, for running without a real dependency. Each
+// call draws from its own source derived from rng for its place in the
+// sequence of calls, so a seed replays the same latencies and failures even
+// when calls run concurrently.
 type UnstableAPI struct {
+	rng   *random.Rand
+	calls atomic.Int64
 }
 
-func NewUnstableAPI() UnstableAPI {
-	return UnstableAPI{}
+func NewUnstableAPI(rng *random.Rand) *UnstableAPI {
+	return &UnstableAPI{rng: rng}
 }
 
 func (a *UnstableAPI) VolatileCall(ctx context.Context) (context.Context, error) {
 	ctx, complete := instrumentation.Tracer.Span(ctx, "external.UnstableAPI.VolatileCall")
 	defer complete()
 
-	ctx, err := a.simulateLatency(ctx)
+	rng := a.rng.Derive(a.calls.Add(1))
+
+	ctx, err := a.simulateLatency(ctx, rng)
 	if err != nil {
 		return ctx, err
 	}
 
-	ctx, err = a.simulateErr(ctx)
+	ctx, err = a.simulateErr(ctx, rng)
 	if err != nil {
 		return ctx, err
 	}
@@ -54,32 +64,39 @@ func (a *UnstableAPI) VolatileCall(ctx context.Context) (context.Context, error)
 	return ctx, nil
 }
 
-func (a *UnstableAPI) simulateLatency(ctx context.Context) (context.Context, error) {
+func (a *UnstableAPI) simulateLatency(ctx context.Context, rng *random.Rand) (context.Context, error) {
 	traceID := instrumentation.Tracer.GetTraceIDFromContext(ctx)
 
 	ctx, complete := instrumentation.Tracer.Span(ctx, "external.UnstableAPI.simulateLatency")
 	defer complete()
 
-	latency := latency[rand.Intn(len(latency))]
+	level := rng.Intn(len(latency))
+
+	instrumentation.Logger.Debug("Simulating latency", "latency", latency[level], "traceId", traceID)
 
-	instrumentation.Logger.Debug("Simulating latency", "latency", latency, "traceId", traceID)
+	timer := time.NewTimer(time.Duration(latency[level]) * time.Millisecond)
+	defer timer.Stop()
 
-	time.Sleep(time.Duration(latency) * time.Millisecond)
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return ctx, ctx.Err()
+	}
 
-	if latency == unreliableLatency {
+	if level == unreliableLatency {
 		return ctx, ErrUnstable
 	}
 
 	return ctx, nil
 }
 
-func (a *UnstableAPI) simulateErr(ctx context.Context) (context.Context, error) {
+func (a *UnstableAPI) simulateErr(ctx context.Context, rng *random.Rand) (context.Context, error) {
 	ctx, complete := instrumentation.Tracer.Span(ctx, "external.UnstableAPI.simulateErr")
 	defer complete()
 
 	traceID := instrumentation.Tracer.GetTraceIDFromContext(ctx)
 
-	shouldErr := rand.Float64() < errProbability
+	shouldErr := rng.Float64() < errProbability
 
 	instrumentation.Logger.Debug("Simulating error", "shouldErr", shouldErr, "traceId", traceID)
 
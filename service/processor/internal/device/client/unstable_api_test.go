@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/charmingruby/devicio/lib/core/random"
+)
+
+// seedForLevel finds a seed whose first latency draw picks level.
+func seedForLevel(t *testing.T, level int) int64 {
+	t.Helper()
+
+	for seed := int64(1); seed < 1000; seed++ {
+		if random.New(seed).Intn(len(latency)) == level {
+			return seed
+		}
+	}
+
+	t.Fatalf("no seed draws latency level %d", level)
+
+	return 0
+}
+
+func TestUnstableAPIFailsAtUnreliableLatency(t *testing.T) {
+	tests := []struct {
+		name  string
+		level int
+		want  error
+	}{
+		{name: "low latency", level: lowLatency},
+		{name: "unreliable latency", level: unreliableLatency, want: ErrUnstable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := NewUnstableAPI(random.New(1))
+
+			_, err := api.simulateLatency(context.Background(), random.New(seedForLevel(t, tt.level)))
+			if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Fatalf("simulateLatency error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+package device
+
+import "context"
+
+// ExternalAPI is the downstream dependency called for every processed
+// routine. Implementations return client.ErrUnstable for transient failures
+// and client.ErrUnknown for failures not worth retrying.
+type ExternalAPI interface {
+	VolatileCall(ctx context.Context) (context.Context, error)
+}
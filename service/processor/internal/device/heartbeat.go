@@ -0,0 +1,179 @@
+package device
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/messaging"
+	"github.com/charmingruby/devicio/lib/proto/gen/pb"
+	"github.com/charmingruby/devicio/service/processor/pkg/instrumentation"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultHeartbeatInterval   = 30 * time.Second
+	defaultHeartbeatStaleAfter = 5 * time.Minute
+)
+
+type HeartbeatConfig struct {
+	// Interval is how often devices are checked. Defaults to 30s.
+	Interval time.Duration
+	// StaleAfter is how long a device may stay silent before it is flagged.
+	// Defaults to 5m.
+	StaleAfter time.Duration
+	// AreaStaleAfter overrides StaleAfter for devices in specific areas.
+	AreaStaleAfter map[string]time.Duration
+}
+
+func (c *HeartbeatConfig) interval() time.Duration {
+	if c.Interval <= 0 {
+		return defaultHeartbeatInterval
+	}
+
+	return c.Interval
+}
+
+func (c *HeartbeatConfig) staleAfter(area string) time.Duration {
+	if d, ok := c.AreaStaleAfter[area]; ok && d > 0 {
+		return d
+	}
+
+	if c.StaleAfter <= 0 {
+		return defaultHeartbeatStaleAfter
+	}
+
+	return c.StaleAfter
+}
+
+// shortestStaleAfter bounds the candidate query so every area's threshold is
+// covered by a single scan.
+func (c *HeartbeatConfig) shortestStaleAfter() time.Duration {
+	shortest := c.staleAfter("")
+
+	for area := range c.AreaStaleAfter {
+		if d := c.staleAfter(area); d < shortest {
+			shortest = d
+		}
+	}
+
+	return shortest
+}
+
+// HeartbeatMonitor periodically flags devices whose last dispatched routine
+// is older than their area's threshold. Each device is flagged once until it
+// reports again; flagging goes through the database so several processor
+// instances emit a single DeviceHeartbeatMissed event per silence.
+type HeartbeatMonitor struct {
+	cfg     HeartbeatConfig
+	devices DeviceRepository
+	events  messaging.Queue
+	now     func() time.Time
+
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+func NewHeartbeatMonitor(cfg HeartbeatConfig, devices DeviceRepository, events messaging.Queue) *HeartbeatMonitor {
+	m := &HeartbeatMonitor{
+		cfg:     cfg,
+		devices: devices,
+		events:  events,
+		now:     func() time.Time { return time.Now().UTC() },
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	go m.run()
+
+	return m
+}
+
+func (m *HeartbeatMonitor) run() {
+	defer close(m.stopped)
+
+	ticker := time.NewTicker(m.cfg.interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.check()
+		case <-m.done:
+			return
+		}
+	}
+}
+
+func (m *HeartbeatMonitor) check() {
+	ctx, complete := instrumentation.Tracer.Span(context.Background(), "device.HeartbeatMonitor.check")
+	defer complete()
+
+	now := m.now()
+
+	candidates, ctx, err := m.devices.ListStaleCandidates(ctx, now.Add(-m.cfg.shortestStaleAfter()))
+	if err != nil {
+		instrumentation.Logger.Error("Failed to list stale device candidates", "error", err)
+		return
+	}
+
+	for _, d := range candidates {
+		cutoff := now.Add(-m.cfg.staleAfter(d.Area))
+		if !d.LastSeenAt.Before(cutoff) {
+			continue
+		}
+
+		stale, flagged, _, err := m.devices.MarkStale(ctx, d.ID, cutoff, now)
+		if err != nil {
+			instrumentation.Logger.Error("Failed to flag stale device", "deviceId", d.ID, "error", err)
+			continue
+		}
+
+		if !flagged {
+			continue
+		}
+
+		instrumentation.Logger.Warn("Device went silent",
+			"deviceId", stale.ID,
+			"area", stale.Area,
+			"lastSeenAt", stale.LastSeenAt,
+		)
+
+		if _, err := m.events.Publish(ctx, &pb.DeviceHeartbeatMissed{
+			DeviceId:   stale.ID,
+			Area:       stale.Area,
+			LastSeenAt: timestamppb.New(stale.LastSeenAt),
+			DetectedAt: timestamppb.New(now),
+		}); err != nil {
+			instrumentation.Logger.Error("Failed to publish device heartbeat missed event", "deviceId", stale.ID, "error", err)
+		}
+	}
+
+	m.updateStaleDevicesMetric(ctx)
+}
+
+func (m *HeartbeatMonitor) updateStaleDevicesMetric(ctx context.Context) {
+	stale, _, err := m.devices.ListStale(ctx)
+	if err != nil {
+		instrumentation.Logger.Error("Failed to list stale devices", "error", err)
+		return
+	}
+
+	staleDevicesGaugeMetric, err := instrumentation.StaleDevicesGaugeMetric()
+	if err != nil {
+		instrumentation.Logger.Error("Failed to get stale devices gauge metric", "error", err)
+		return
+	}
+
+	staleDevicesGaugeMetric.Set(float64(len(stale)))
+}
+
+// Close stops the monitor, waiting for an in-progress check to finish.
+func (m *HeartbeatMonitor) Close() {
+	m.closeOnce.Do(func() {
+		close(m.done)
+	})
+
+	<-m.stopped
+}
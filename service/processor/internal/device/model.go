@@ -2,13 +2,39 @@ package device
 
 import "time"
 
+type Device struct {
+	ID         string     `db:"id"`
+	Status     string     `db:"status"`
+	Area       string     `db:"area"`
+	LastSeenAt time.Time  `db:"last_seen_at"`
+	StaleSince *time.Time `db:"stale_since"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
+
 type Routine struct {
-	ID           string
-	DeviceID     string
-	Status       string
-	Context      string
-	Area         string
-	Diagnostics  string
-	DispatchedAt time.Time
-	CreatedAt    time.Time
+	ID           string    `db:"id"`
+	MessageID    string    `db:"message_id"`
+	TraceID      string    `db:"trace_id"`
+	DeviceID     string    `db:"device_id"`
+	Status       string    `db:"status"`
+	Context      string    `db:"context"`
+	Area         string    `db:"area"`
+	Diagnostics  string    `db:"diagnostics"`
+	DispatchedAt time.Time `db:"dispatched_at"`
+	CreatedAt    time.Time `db:"created_at"`
+	// SightedAt is set once the routine's device sighting is recorded.
+	SightedAt *time.Time `db:"sighted_at"`
+}
+
+// RoutineFilter narrows a routine listing. Zero values match everything.
+// Results are ordered by ID, and After resumes a listing from the ID of the
+// last routine of a previous page.
+type RoutineFilter struct {
+	DeviceID string
+	Area     string
+	Status   string
+	From     time.Time
+	To       time.Time
+	After    string
+	Limit    int
 }
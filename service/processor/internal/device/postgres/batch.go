@@ -0,0 +1,331 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/core/id"
+	"github.com/charmingruby/devicio/service/processor/internal/device"
+	"github.com/charmingruby/devicio/service/processor/pkg/instrumentation"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	defaultBatchSize          = 100
+	defaultBatchFlushInterval = 50 * time.Millisecond
+)
+
+var (
+	ErrRepositoryClosed = errors.New("repository closed")
+
+	routineColumns = []string{
+		"id", "message_id", "trace_id", "device_id", "status",
+		"context", "area", "dispatched_at", "created_at",
+	}
+
+	// maxBatchSize keeps a multi-row insert under Postgres' limit of 65535
+	// bind parameters.
+	maxBatchSize = 65535 / len(routineColumns)
+)
+
+type BatchConfig struct {
+	// Size is the number of routines that triggers a flush. Each Store call
+	// blocks until its batch is written, so a batch only fills before the
+	// flush interval when at least Size calls run concurrently; keep it at
+	// or below the consumer concurrency.
+	Size int
+	// FlushInterval bounds how long a routine waits for its batch to fill.
+	FlushInterval time.Duration
+}
+
+func (c *BatchConfig) size() int {
+	switch {
+	case c.Size <= 0:
+		return defaultBatchSize
+	case c.Size > maxBatchSize:
+		return maxBatchSize
+	default:
+		return c.Size
+	}
+}
+
+func (c *BatchConfig) flushInterval() time.Duration {
+	if c.FlushInterval <= 0 {
+		return defaultBatchFlushInterval
+	}
+
+	return c.FlushInterval
+}
+
+type batchRequest struct {
+	routine device.Routine
+	result  chan batchResult
+}
+
+type batchResult struct {
+	routine device.Routine
+	err     error
+}
+
+// BatchRoutineRepository buffers routines from concurrent Store calls and
+// writes them with multi-row inserts, flushing when the batch is full or the
+// flush interval elapses. Store blocks until the routine's batch commits, so
+// a handler acks its delivery only once the routine is durable.
+type BatchRoutineRepository struct {
+	*RoutineRepository
+
+	cfg       BatchConfig
+	requests  chan batchRequest
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+func NewBatchRoutineRepository(db *sqlx.DB, cfg BatchConfig) (*BatchRoutineRepository, error) {
+	repo, err := NewRoutineRepository(db)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &BatchRoutineRepository{
+		RoutineRepository: repo,
+		cfg:               cfg,
+		requests:          make(chan batchRequest),
+		done:              make(chan struct{}),
+		stopped:           make(chan struct{}),
+	}
+
+	go r.run()
+
+	return r, nil
+}
+
+func (r *BatchRoutineRepository) Store(ctx context.Context, routine device.Routine) (device.Routine, context.Context, error) {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "repository.BatchRoutineRepository.Store")
+	defer complete()
+
+	req := batchRequest{
+		routine: routine,
+		result:  make(chan batchResult, 1),
+	}
+
+	select {
+	case r.requests <- req:
+	case <-r.done:
+		return device.Routine{}, ctx, ErrRepositoryClosed
+	case <-ctx.Done():
+		return device.Routine{}, ctx, ctx.Err()
+	}
+
+	// Once enqueued the routine is written regardless of ctx, so wait for the
+	// outcome rather than reporting a failure for a routine that was stored.
+	res := <-req.result
+
+	return res.routine, ctx, res.err
+}
+
+// Close flushes buffered routines and stops the batching loop. Store calls
+// made after Close fail with ErrRepositoryClosed.
+func (r *BatchRoutineRepository) Close() {
+	r.closeOnce.Do(func() {
+		close(r.done)
+	})
+
+	<-r.stopped
+}
+
+func (r *BatchRoutineRepository) run() {
+	defer close(r.stopped)
+
+	var (
+		batch []batchRequest
+		timer *time.Timer
+		flush <-chan time.Time
+	)
+
+	emit := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, flush = nil, nil
+		}
+
+		if len(batch) == 0 {
+			return
+		}
+
+		r.flush(batch)
+		batch = nil
+	}
+
+	for {
+		select {
+		case req := <-r.requests:
+			batch = append(batch, req)
+
+			if len(batch) == 1 {
+				timer = time.NewTimer(r.cfg.flushInterval())
+				flush = timer.C
+			}
+
+			if len(batch) >= r.cfg.size() {
+				emit()
+			}
+		case <-flush:
+			timer, flush = nil, nil
+			emit()
+		case <-r.done:
+			emit()
+			return
+		}
+	}
+}
+
+func (r *BatchRoutineRepository) flush(batch []batchRequest) {
+	ctx, complete := instrumentation.Tracer.Span(context.Background(), "repository.BatchRoutineRepository.flush")
+	defer complete()
+
+	stored, err := r.insertBatch(ctx, batch)
+	if err != nil && len(batch) > 1 {
+		// One bad routine fails the whole multi-row insert, so store them
+		// individually to keep it from failing its neighbours.
+		instrumentation.Logger.Warn("Failed to flush routine batch, storing routines individually", "size", len(batch), "error", err)
+
+		r.storeEach(ctx, batch)
+		return
+	}
+
+	if err != nil {
+		instrumentation.Logger.Error("Failed to flush routine batch", "size", len(batch), "error", err)
+	} else {
+		instrumentation.Logger.Debug("Flushed routine batch", "size", len(batch), "stored", len(stored))
+	}
+
+	for _, req := range batch {
+		if err != nil {
+			req.result <- batchResult{err: err}
+			continue
+		}
+
+		routine, ok := stored[req.routine.ID]
+		if !ok {
+			req.result <- batchResult{err: device.ErrRoutineAlreadyProcessed}
+			continue
+		}
+
+		req.result <- batchResult{routine: routine}
+	}
+}
+
+// storeEach writes each routine of a failed batch in its own transaction and
+// reports every outcome separately.
+func (r *BatchRoutineRepository) storeEach(ctx context.Context, batch []batchRequest) {
+	for _, req := range batch {
+		routine, _, err := r.RoutineRepository.Store(ctx, req.routine)
+		if err != nil && !errors.Is(err, device.ErrRoutineAlreadyProcessed) {
+			instrumentation.Logger.Error("Failed to store routine", "routineId", req.routine.ID, "error", err)
+		}
+
+		req.result <- batchResult{routine: routine, err: err}
+	}
+}
+
+// insertBatch writes the batch in one transaction and returns the stored rows
+// keyed by routine ID. Routines whose message ID already exists are skipped by
+// the insert and are absent from the result.
+func (r *BatchRoutineRepository) insertBatch(ctx context.Context, batch []batchRequest) (map[string]device.Routine, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	routineArgs := make([]any, 0, len(batch)*len(routineColumns))
+	for _, req := range batch {
+		rt := req.routine
+
+		routineArgs = append(routineArgs,
+			rt.ID, rt.MessageID, rt.TraceID, rt.DeviceID, rt.Status,
+			rt.Context, rt.Area, rt.DispatchedAt, rt.CreatedAt,
+		)
+	}
+
+	query := fmt.Sprintf(`INSERT INTO device_routines
+		(%s)
+		VALUES %s
+		ON CONFLICT (message_id) DO NOTHING
+		RETURNING *`,
+		strings.Join(routineColumns, ", "),
+		valuesPlaceholders(len(batch), len(routineColumns)),
+	)
+
+	var rows []device.Routine
+	if err := tx.SelectContext(ctx, &rows, query, routineArgs...); err != nil {
+		return nil, err
+	}
+
+	stored := make(map[string]device.Routine, len(rows))
+	for _, row := range rows {
+		stored[row.ID] = row
+	}
+
+	var diagnosticArgs []any
+	for _, req := range batch {
+		row, ok := stored[req.routine.ID]
+		if !ok || req.routine.Diagnostics == "" {
+			continue
+		}
+
+		row.Diagnostics = req.routine.Diagnostics
+		stored[row.ID] = row
+
+		diagnosticArgs = append(diagnosticArgs, id.New(), req.routine.Diagnostics, row.ID, req.routine.CreatedAt)
+	}
+
+	if len(diagnosticArgs) > 0 {
+		query := fmt.Sprintf(`INSERT INTO device_routine_diagnostics
+			(id, diagnostic, routine_id, created_at)
+			VALUES %s`,
+			valuesPlaceholders(len(diagnosticArgs)/4, 4),
+		)
+
+		if _, err := tx.ExecContext(ctx, query, diagnosticArgs...); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return stored, nil
+}
+
+// valuesPlaceholders renders rows groups of columns bind parameters, as in
+// ($1, $2), ($3, $4).
+func valuesPlaceholders(rows, columns int) string {
+	var b strings.Builder
+
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		b.WriteByte('(')
+
+		for j := 0; j < columns; j++ {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+
+			fmt.Fprintf(&b, "$%d", i*columns+j+1)
+		}
+
+		b.WriteByte(')')
+	}
+
+	return b.String()
+}
@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/charmingruby/devicio/service/processor/internal/device"
+	"github.com/charmingruby/devicio/service/processor/pkg/instrumentation"
+	"github.com/jmoiron/sqlx"
+)
+
+type nopTracer struct{}
+
+func (nopTracer) Span(ctx context.Context, _ string) (context.Context, func()) {
+	return ctx, func() {}
+}
+
+func (nopTracer) GetTraceIDFromContext(context.Context) string { return "" }
+
+func (nopTracer) Inject(context.Context, map[string]string) {}
+
+func (nopTracer) Extract(ctx context.Context, _ map[string]string) context.Context {
+	return ctx
+}
+
+func (nopTracer) Close() error { return nil }
+
+func TestMain(m *testing.M) {
+	instrumentation.NewLogger("error")
+	instrumentation.Tracer = nopTracer{}
+
+	os.Exit(m.Run())
+}
+
+func TestValuesPlaceholders(t *testing.T) {
+	tests := []struct {
+		rows, columns int
+		want          string
+	}{
+		{rows: 0, columns: 3, want: ""},
+		{rows: 1, columns: 1, want: "($1)"},
+		{rows: 1, columns: 3, want: "($1, $2, $3)"},
+		{rows: 2, columns: 2, want: "($1, $2), ($3, $4)"},
+		{rows: 3, columns: 1, want: "($1), ($2), ($3)"},
+	}
+
+	for _, tt := range tests {
+		if got := valuesPlaceholders(tt.rows, tt.columns); got != tt.want {
+			t.Errorf("valuesPlaceholders(%d, %d) = %q, want %q", tt.rows, tt.columns, got, tt.want)
+		}
+	}
+}
+
+func TestBatchConfigSize(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		want int
+	}{
+		{name: "unset", size: 0, want: defaultBatchSize},
+		{name: "negative", size: -5, want: defaultBatchSize},
+		{name: "within bounds", size: 25, want: 25},
+		{name: "at the limit", size: maxBatchSize, want: maxBatchSize},
+		{name: "over the bind parameter limit", size: maxBatchSize + 1, want: maxBatchSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := BatchConfig{Size: tt.size}
+
+			if got := cfg.size(); got != tt.want {
+				t.Fatalf("size() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlushReportsSkippedRoutinesAsAlreadyProcessed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := &BatchRoutineRepository{
+		RoutineRepository: &RoutineRepository{db: sqlx.NewDb(db, "postgres")},
+	}
+
+	now := time.Now()
+	fresh := device.Routine{ID: "routine-1", MessageID: "message-1", DeviceID: "device-1", DispatchedAt: now, CreatedAt: now}
+	duplicate := device.Routine{ID: "routine-2", MessageID: "message-2", DeviceID: "device-1", DispatchedAt: now, CreatedAt: now}
+
+	// the insert skips the duplicate's conflicting message ID, so only the
+	// fresh routine comes back
+	rows := sqlmock.NewRows(routineColumns).AddRow(
+		fresh.ID, fresh.MessageID, fresh.TraceID, fresh.DeviceID, fresh.Status,
+		fresh.Context, fresh.Area, fresh.DispatchedAt, fresh.CreatedAt,
+	)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO device_routines")).WillReturnRows(rows)
+	mock.ExpectCommit()
+
+	batch := []batchRequest{
+		{routine: fresh, result: make(chan batchResult, 1)},
+		{routine: duplicate, result: make(chan batchResult, 1)},
+	}
+
+	repo.flush(batch)
+
+	if res := <-batch[0].result; res.err != nil || res.routine.ID != fresh.ID {
+		t.Fatalf("fresh routine result = (%q, %v), want (%q, nil)", res.routine.ID, res.err, fresh.ID)
+	}
+
+	if res := <-batch[1].result; !errors.Is(res.err, device.ErrRoutineAlreadyProcessed) {
+		t.Fatalf("duplicate routine error = %v, want %v", res.err, device.ErrRoutineAlreadyProcessed)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
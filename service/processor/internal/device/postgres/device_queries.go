@@ -0,0 +1,58 @@
+package postgres
+
+const (
+	registerDevice         = "register device"
+	upsertDevice           = "upsert device"
+	findDeviceByID         = "find device by id"
+	createStatusTransition = "create status transition"
+	markRoutineSighted     = "mark routine sighted"
+	listStatusTransitions  = "list status transitions by device"
+	listStaleCandidates    = "list stale device candidates"
+	markDeviceStale        = "mark device stale"
+	listStaleDevices       = "list stale devices"
+)
+
+func deviceQueries() map[string]string {
+	return map[string]string{
+		registerDevice: `INSERT INTO devices
+		(id, status, area, last_seen_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (id) DO NOTHING`,
+		// The previous CTE locks the device row and reports its status from
+		// before the upsert, so concurrent sightings see a consistent history.
+		upsertDevice: `WITH previous AS (
+			SELECT status FROM devices WHERE id = $1 FOR UPDATE
+		), upserted AS (
+			INSERT INTO devices
+			(id, status, area, last_seen_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (id) DO UPDATE SET
+			status = CASE WHEN EXCLUDED.last_seen_at >= devices.last_seen_at THEN EXCLUDED.status ELSE devices.status END,
+			area = CASE WHEN EXCLUDED.last_seen_at >= devices.last_seen_at THEN EXCLUDED.area ELSE devices.area END,
+			last_seen_at = GREATEST(devices.last_seen_at, EXCLUDED.last_seen_at),
+			stale_since = CASE WHEN EXCLUDED.last_seen_at >= devices.last_seen_at THEN NULL ELSE devices.stale_since END
+			RETURNING *
+		)
+		SELECT upserted.*, COALESCE((SELECT status FROM previous), '') AS previous_status
+		FROM upserted`,
+		findDeviceByID: `SELECT * FROM devices WHERE id = $1`,
+		createStatusTransition: `INSERT INTO device_status_transitions
+		(id, device_id, routine_id, from_status, to_status, kind, area, occurred_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		markRoutineSighted: `UPDATE device_routines SET sighted_at = now()
+		WHERE id = $1`,
+		listStatusTransitions: `SELECT * FROM device_status_transitions
+		WHERE device_id = $1
+		ORDER BY occurred_at DESC, id DESC
+		LIMIT $2`,
+		listStaleCandidates: `SELECT * FROM devices
+		WHERE stale_since IS NULL AND last_seen_at < $1
+		ORDER BY last_seen_at`,
+		markDeviceStale: `UPDATE devices SET stale_since = $3
+		WHERE id = $1 AND stale_since IS NULL AND last_seen_at < $2
+		RETURNING *`,
+		listStaleDevices: `SELECT * FROM devices
+		WHERE stale_since IS NOT NULL
+		ORDER BY last_seen_at`,
+	}
+}
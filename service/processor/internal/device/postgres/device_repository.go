@@ -0,0 +1,229 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/database"
+	"github.com/charmingruby/devicio/service/processor/internal/device"
+	"github.com/charmingruby/devicio/service/processor/pkg/instrumentation"
+	"github.com/jmoiron/sqlx"
+)
+
+func NewDeviceRepository(db *sqlx.DB) (*DeviceRepository, error) {
+	stmts := make(map[string]*sqlx.Stmt)
+
+	for queryName, statement := range deviceQueries() {
+		stmt, err := db.Preparex(statement)
+		if err != nil {
+			instrumentation.Logger.Error(fmt.Sprintf("unable to prepare the query: %s, err: %s", queryName, err.Error()))
+			return nil, database.ErrPreparation
+		}
+
+		stmts[queryName] = stmt
+	}
+
+	return &DeviceRepository{
+		db:    db,
+		stmts: stmts,
+	}, nil
+}
+
+type DeviceRepository struct {
+	db    *sqlx.DB
+	stmts map[string]*sqlx.Stmt
+}
+
+func (r *DeviceRepository) statement(queryName string) (*sqlx.Stmt, error) {
+	stmt, ok := r.stmts[queryName]
+
+	if !ok {
+		instrumentation.Logger.Error(fmt.Sprintf("statement not prepared: %s", queryName))
+		return nil, database.ErrStatementNotPrepared
+	}
+
+	return stmt, nil
+}
+
+func (r *DeviceRepository) Register(ctx context.Context, d device.Device) (context.Context, error) {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "repository.DeviceRepository.Register")
+	defer complete()
+
+	stmt, err := r.statement(registerDevice)
+	if err != nil {
+		return ctx, err
+	}
+
+	if _, err := stmt.ExecContext(ctx, d.ID, d.Status, d.Area, d.LastSeenAt); err != nil {
+		return ctx, err
+	}
+
+	return ctx, nil
+}
+
+func (r *DeviceRepository) RecordSighting(ctx context.Context, d device.Device, routineID string) (device.Sighting, context.Context, error) {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "repository.DeviceRepository.RecordSighting")
+	defer complete()
+
+	upsertStmt, err := r.statement(upsertDevice)
+	if err != nil {
+		return device.Sighting{}, ctx, err
+	}
+
+	transitionStmt, err := r.statement(createStatusTransition)
+	if err != nil {
+		return device.Sighting{}, ctx, err
+	}
+
+	sightedStmt, err := r.statement(markRoutineSighted)
+	if err != nil {
+		return device.Sighting{}, ctx, err
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return device.Sighting{}, ctx, err
+	}
+	defer tx.Rollback()
+
+	var row struct {
+		device.Device
+		PreviousStatus string `db:"previous_status"`
+	}
+
+	if err := tx.StmtxContext(ctx, upsertStmt).GetContext(ctx, &row,
+		d.ID,
+		d.Status,
+		d.Area,
+		d.LastSeenAt,
+	); err != nil {
+		return device.Sighting{}, ctx, err
+	}
+
+	sighting := device.Sighting{Device: row.Device}
+
+	if t, ok := device.DetectTransition(row.PreviousStatus, row.Device, routineID); ok {
+		if _, err := tx.StmtxContext(ctx, transitionStmt).ExecContext(ctx,
+			t.ID,
+			t.DeviceID,
+			t.RoutineID,
+			t.FromStatus,
+			t.ToStatus,
+			t.Kind,
+			t.Area,
+			t.OccurredAt,
+			t.CreatedAt,
+		); err != nil {
+			return device.Sighting{}, ctx, err
+		}
+
+		sighting.Transition = &t
+	}
+
+	if _, err := tx.StmtxContext(ctx, sightedStmt).ExecContext(ctx, routineID); err != nil {
+		return device.Sighting{}, ctx, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return device.Sighting{}, ctx, err
+	}
+
+	return sighting, ctx, nil
+}
+
+func (r *DeviceRepository) FindByID(ctx context.Context, id string) (device.Device, context.Context, error) {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "repository.DeviceRepository.FindByID")
+	defer complete()
+
+	stmt, err := r.statement(findDeviceByID)
+	if err != nil {
+		return device.Device{}, ctx, err
+	}
+
+	var d device.Device
+	if err := stmt.GetContext(ctx, &d, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return device.Device{}, ctx, device.ErrDeviceNotFound
+		}
+
+		return device.Device{}, ctx, err
+	}
+
+	return d, ctx, nil
+}
+
+func (r *DeviceRepository) ListTransitions(ctx context.Context, deviceID string, limit int) ([]device.StatusTransition, context.Context, error) {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "repository.DeviceRepository.ListTransitions")
+	defer complete()
+
+	stmt, err := r.statement(listStatusTransitions)
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	transitions := []device.StatusTransition{}
+	if err := stmt.SelectContext(ctx, &transitions, deviceID, limit); err != nil {
+		return nil, ctx, err
+	}
+
+	return transitions, ctx, nil
+}
+
+func (r *DeviceRepository) ListStaleCandidates(ctx context.Context, lastSeenBefore time.Time) ([]device.Device, context.Context, error) {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "repository.DeviceRepository.ListStaleCandidates")
+	defer complete()
+
+	stmt, err := r.statement(listStaleCandidates)
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	devices := []device.Device{}
+	if err := stmt.SelectContext(ctx, &devices, lastSeenBefore); err != nil {
+		return nil, ctx, err
+	}
+
+	return devices, ctx, nil
+}
+
+func (r *DeviceRepository) MarkStale(ctx context.Context, id string, lastSeenBefore, at time.Time) (device.Device, bool, context.Context, error) {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "repository.DeviceRepository.MarkStale")
+	defer complete()
+
+	stmt, err := r.statement(markDeviceStale)
+	if err != nil {
+		return device.Device{}, false, ctx, err
+	}
+
+	var d device.Device
+	if err := stmt.GetContext(ctx, &d, id, lastSeenBefore, at); err != nil {
+		// Another instance flagged the device, or it reported again.
+		if errors.Is(err, sql.ErrNoRows) {
+			return device.Device{}, false, ctx, nil
+		}
+
+		return device.Device{}, false, ctx, err
+	}
+
+	return d, true, ctx, nil
+}
+
+func (r *DeviceRepository) ListStale(ctx context.Context) ([]device.Device, context.Context, error) {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "repository.DeviceRepository.ListStale")
+	defer complete()
+
+	stmt, err := r.statement(listStaleDevices)
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	devices := []device.Device{}
+	if err := stmt.SelectContext(ctx, &devices); err != nil {
+		return nil, ctx, err
+	}
+
+	return devices, ctx, nil
+}
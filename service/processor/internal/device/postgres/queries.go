@@ -1,14 +1,43 @@
 package postgres
 
 const (
-	createRoutine = "create routine"
+	createRoutine           = "create routine"
+	createRoutineDiagnostic = "create routine diagnostic"
+	findRoutineByMessageID  = "find routine by message id"
+	findRoutineByID         = "find routine by id"
+	listRoutines            = "list routines"
 )
 
 func routineQueries() map[string]string {
 	return map[string]string{
 		createRoutine: `INSERT INTO device_routines
-		(id, device_id, status, context, area, dispatched_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		(id, message_id, trace_id, device_id, status, context, area, dispatched_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (message_id) DO NOTHING
 		RETURNING *`,
+		createRoutineDiagnostic: `INSERT INTO device_routine_diagnostics
+		(id, diagnostic, routine_id, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING diagnostic`,
+		findRoutineByMessageID: `SELECT * FROM device_routines WHERE message_id = $1`,
+		findRoutineByID: `SELECT r.*,
+		COALESCE(string_agg(d.diagnostic, E'\n' ORDER BY d.created_at), '') AS diagnostics
+		FROM device_routines r
+		LEFT JOIN device_routine_diagnostics d ON d.routine_id = r.id
+		WHERE r.id = $1
+		GROUP BY r.id`,
+		listRoutines: `SELECT r.*,
+		COALESCE(string_agg(d.diagnostic, E'\n' ORDER BY d.created_at), '') AS diagnostics
+		FROM device_routines r
+		LEFT JOIN device_routine_diagnostics d ON d.routine_id = r.id
+		WHERE ($1 = '' OR r.device_id = $1)
+		AND ($2 = '' OR r.area = $2)
+		AND ($3 = '' OR r.status = $3)
+		AND ($4::timestamp IS NULL OR r.dispatched_at >= $4)
+		AND ($5::timestamp IS NULL OR r.dispatched_at < $5)
+		AND ($6 = '' OR r.id > $6)
+		GROUP BY r.id
+		ORDER BY r.id
+		LIMIT $7`,
 	}
 }
@@ -2,8 +2,12 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/charmingruby/devicio/lib/core/id"
 	"github.com/charmingruby/devicio/lib/database"
 	"github.com/charmingruby/devicio/service/processor/internal/device"
 	"github.com/charmingruby/devicio/service/processor/pkg/instrumentation"
@@ -45,25 +49,132 @@ func (r *RoutineRepository) statement(queryName string) (*sqlx.Stmt, error) {
 	return stmt, nil
 }
 
-func (r *RoutineRepository) Store(ctx context.Context, routine device.Routine) (context.Context, error) {
+func (r *RoutineRepository) Store(ctx context.Context, routine device.Routine) (device.Routine, context.Context, error) {
 	ctx, complete := instrumentation.Tracer.Span(ctx, "repository.RoutineRepository.Store")
 	defer complete()
 
-	stmt, err := r.statement(createRoutine)
+	routineStmt, err := r.statement(createRoutine)
 	if err != nil {
-		return ctx, err
+		return device.Routine{}, ctx, err
 	}
 
-	if _, err := stmt.Exec(
+	diagnosticStmt, err := r.statement(createRoutineDiagnostic)
+	if err != nil {
+		return device.Routine{}, ctx, err
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return device.Routine{}, ctx, err
+	}
+	defer tx.Rollback()
+
+	var stored device.Routine
+
+	if err := tx.StmtxContext(ctx, routineStmt).GetContext(ctx, &stored,
 		routine.ID,
+		routine.MessageID,
+		routine.TraceID,
 		routine.DeviceID,
 		routine.Status,
 		routine.Context,
 		routine.Area,
 		routine.DispatchedAt,
+		routine.CreatedAt,
+	); err != nil {
+		// The insert returns no row when the message ID conflicts.
+		if errors.Is(err, sql.ErrNoRows) {
+			return device.Routine{}, ctx, device.ErrRoutineAlreadyProcessed
+		}
+
+		return device.Routine{}, ctx, err
+	}
+
+	if routine.Diagnostics != "" {
+		if err := tx.StmtxContext(ctx, diagnosticStmt).GetContext(ctx, &stored.Diagnostics,
+			id.New(),
+			routine.Diagnostics,
+			stored.ID,
+			routine.CreatedAt,
+		); err != nil {
+			return device.Routine{}, ctx, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return device.Routine{}, ctx, err
+	}
+
+	return stored, ctx, nil
+}
+
+func (r *RoutineRepository) FindByMessageID(ctx context.Context, messageID string) (device.Routine, context.Context, error) {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "repository.RoutineRepository.FindByMessageID")
+	defer complete()
+
+	stmt, err := r.statement(findRoutineByMessageID)
+	if err != nil {
+		return device.Routine{}, ctx, err
+	}
+
+	var routine device.Routine
+	if err := stmt.GetContext(ctx, &routine, messageID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return device.Routine{}, ctx, device.ErrRoutineNotFound
+		}
+
+		return device.Routine{}, ctx, err
+	}
+
+	return routine, ctx, nil
+}
+
+func (r *RoutineRepository) FindByID(ctx context.Context, id string) (device.Routine, context.Context, error) {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "repository.RoutineRepository.FindByID")
+	defer complete()
+
+	stmt, err := r.statement(findRoutineByID)
+	if err != nil {
+		return device.Routine{}, ctx, err
+	}
+
+	var routine device.Routine
+	if err := stmt.GetContext(ctx, &routine, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return device.Routine{}, ctx, device.ErrRoutineNotFound
+		}
+
+		return device.Routine{}, ctx, err
+	}
+
+	return routine, ctx, nil
+}
+
+func (r *RoutineRepository) List(ctx context.Context, filter device.RoutineFilter) ([]device.Routine, context.Context, error) {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "repository.RoutineRepository.List")
+	defer complete()
+
+	stmt, err := r.statement(listRoutines)
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	routines := []device.Routine{}
+	if err := stmt.SelectContext(ctx, &routines,
+		filter.DeviceID,
+		filter.Area,
+		filter.Status,
+		nullableTime(filter.From),
+		nullableTime(filter.To),
+		filter.After,
+		filter.Limit,
 	); err != nil {
-		return ctx, err
+		return nil, ctx, err
 	}
 
-	return ctx, nil
+	return routines, ctx, nil
+}
+
+func nullableTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
 }
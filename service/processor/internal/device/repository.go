@@ -1,7 +1,46 @@
 package device
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrRoutineAlreadyProcessed = errors.New("routine already processed")
+	ErrRoutineNotFound         = errors.New("routine not found")
+	ErrDeviceNotFound          = errors.New("device not found")
+)
 
 type RoutineRepository interface {
-	Store(ctx context.Context, r Routine) (context.Context, error)
+	// Store persists the routine and its diagnostics and returns the stored
+	// row, or ErrRoutineAlreadyProcessed when a routine with the same message
+	// ID has already been stored.
+	Store(ctx context.Context, r Routine) (Routine, context.Context, error)
+	// FindByMessageID returns the routine stored for a message, or
+	// ErrRoutineNotFound when the message has not been stored.
+	FindByMessageID(ctx context.Context, messageID string) (Routine, context.Context, error)
+	FindByID(ctx context.Context, id string) (Routine, context.Context, error)
+	List(ctx context.Context, filter RoutineFilter) ([]Routine, context.Context, error)
+}
+
+type DeviceRepository interface {
+	// Register inserts an unknown device and leaves known ones untouched, so
+	// that routines referencing it can be stored before the sighting is
+	// recorded.
+	Register(ctx context.Context, d Device) (context.Context, error)
+	// RecordSighting registers an unknown device or records a new sighting of
+	// a known one, persisting a status transition when its status changed and
+	// marking the routine as sighted in the same transaction. Sightings older
+	// than the last one seen do not overwrite its state.
+	RecordSighting(ctx context.Context, d Device, routineID string) (Sighting, context.Context, error)
+	FindByID(ctx context.Context, id string) (Device, context.Context, error)
+	ListTransitions(ctx context.Context, deviceID string, limit int) ([]StatusTransition, context.Context, error)
+	// ListStaleCandidates returns devices not yet flagged as stale whose last
+	// sighting is before the given time.
+	ListStaleCandidates(ctx context.Context, lastSeenBefore time.Time) ([]Device, context.Context, error)
+	// MarkStale flags a device as stale, reporting false when it was already
+	// flagged or has been seen again since lastSeenBefore.
+	MarkStale(ctx context.Context, id string, lastSeenBefore, at time.Time) (Device, bool, context.Context, error)
+	ListStale(ctx context.Context) ([]Device, context.Context, error)
 }
@@ -0,0 +1,194 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/charmingruby/devicio/service/processor/internal/device"
+	"github.com/charmingruby/devicio/service/processor/pkg/instrumentation"
+)
+
+type Handler struct {
+	svc *device.Service
+}
+
+func NewHandler(svc *device.Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /routines", h.listRoutines)
+	mux.HandleFunc("GET /routines/{id}", h.getRoutine)
+	mux.HandleFunc("GET /devices/stale", h.listStaleDevices)
+	mux.HandleFunc("GET /devices/{id}", h.getDevice)
+	mux.HandleFunc("GET /devices/{id}/transitions", h.listStatusTransitions)
+}
+
+// listRoutines serves GET /routines. Supported query parameters are
+// device_id, area, status, from and to (RFC 3339, on dispatched_at), cursor
+// and limit.
+func (h *Handler) listRoutines(w http.ResponseWriter, r *http.Request) {
+	ctx, complete := instrumentation.Tracer.Span(extractContext(r), "rest.Handler.listRoutines")
+	defer complete()
+
+	filter, err := parseRoutineFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := h.svc.ListRoutines(ctx, filter)
+	if err != nil {
+		instrumentation.Logger.Error("Failed to list routines", "error", err)
+		writeError(w, http.StatusInternalServerError, errors.New("failed to list routines"))
+		return
+	}
+
+	res := listRoutinesResponse{
+		Data:       make([]routineResponse, 0, len(page.Routines)),
+		NextCursor: page.NextCursor,
+	}
+
+	for _, routine := range page.Routines {
+		res.Data = append(res.Data, newRoutineResponse(routine))
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+// getRoutine serves GET /routines/{id}.
+func (h *Handler) getRoutine(w http.ResponseWriter, r *http.Request) {
+	ctx, complete := instrumentation.Tracer.Span(extractContext(r), "rest.Handler.getRoutine")
+	defer complete()
+
+	routine, err := h.svc.GetRoutine(ctx, r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, device.ErrRoutineNotFound) {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+
+		instrumentation.Logger.Error("Failed to get routine", "error", err)
+		writeError(w, http.StatusInternalServerError, errors.New("failed to get routine"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newRoutineResponse(routine))
+}
+
+// getDevice serves GET /devices/{id}.
+func (h *Handler) getDevice(w http.ResponseWriter, r *http.Request) {
+	ctx, complete := instrumentation.Tracer.Span(extractContext(r), "rest.Handler.getDevice")
+	defer complete()
+
+	d, err := h.svc.GetDevice(ctx, r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, device.ErrDeviceNotFound) {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+
+		instrumentation.Logger.Error("Failed to get device", "error", err)
+		writeError(w, http.StatusInternalServerError, errors.New("failed to get device"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newDeviceResponse(d))
+}
+
+// listStaleDevices serves GET /devices/stale, longest silent first.
+func (h *Handler) listStaleDevices(w http.ResponseWriter, r *http.Request) {
+	ctx, complete := instrumentation.Tracer.Span(extractContext(r), "rest.Handler.listStaleDevices")
+	defer complete()
+
+	devices, err := h.svc.ListStaleDevices(ctx)
+	if err != nil {
+		instrumentation.Logger.Error("Failed to list stale devices", "error", err)
+		writeError(w, http.StatusInternalServerError, errors.New("failed to list stale devices"))
+		return
+	}
+
+	res := listDevicesResponse{Data: make([]deviceResponse, 0, len(devices))}
+	for _, d := range devices {
+		res.Data = append(res.Data, newDeviceResponse(d))
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+// listStatusTransitions serves GET /devices/{id}/transitions, newest first.
+// It accepts an optional limit query parameter.
+func (h *Handler) listStatusTransitions(w http.ResponseWriter, r *http.Request) {
+	ctx, complete := instrumentation.Tracer.Span(extractContext(r), "rest.Handler.listStatusTransitions")
+	defer complete()
+
+	var limit int
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			writeError(w, http.StatusBadRequest, errors.New("invalid limit: must be a positive integer"))
+			return
+		}
+	}
+
+	transitions, err := h.svc.ListStatusTransitions(ctx, r.PathValue("id"), limit)
+	if err != nil {
+		instrumentation.Logger.Error("Failed to list status transitions", "error", err)
+		writeError(w, http.StatusInternalServerError, errors.New("failed to list status transitions"))
+		return
+	}
+
+	res := listStatusTransitionsResponse{Data: make([]statusTransitionResponse, 0, len(transitions))}
+	for _, t := range transitions {
+		res.Data = append(res.Data, newStatusTransitionResponse(t))
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+func parseRoutineFilter(r *http.Request) (device.RoutineFilter, error) {
+	q := r.URL.Query()
+
+	filter := device.RoutineFilter{
+		DeviceID: q.Get("device_id"),
+		Area:     q.Get("area"),
+		Status:   q.Get("status"),
+		After:    q.Get("cursor"),
+	}
+
+	var err error
+
+	if filter.From, err = parseTime(q.Get("from")); err != nil {
+		return device.RoutineFilter{}, fmt.Errorf("invalid from: %w", err)
+	}
+
+	if filter.To, err = parseTime(q.Get("to")); err != nil {
+		return device.RoutineFilter{}, fmt.Errorf("invalid to: %w", err)
+	}
+
+	if limit := q.Get("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit < 1 {
+			return device.RoutineFilter{}, errors.New("invalid limit: must be a positive integer")
+		}
+	}
+
+	return filter, nil
+}
+
+func parseTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// Routine timestamps are stored as UTC without a zone.
+	return t.UTC(), nil
+}
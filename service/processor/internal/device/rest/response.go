@@ -0,0 +1,112 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/charmingruby/devicio/service/processor/internal/device"
+	"github.com/charmingruby/devicio/service/processor/pkg/instrumentation"
+)
+
+type deviceResponse struct {
+	ID         string     `json:"id"`
+	Status     string     `json:"status"`
+	Area       string     `json:"area"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	StaleSince *time.Time `json:"stale_since,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newDeviceResponse(d device.Device) deviceResponse {
+	return deviceResponse{
+		ID:         d.ID,
+		Status:     d.Status,
+		Area:       d.Area,
+		LastSeenAt: d.LastSeenAt,
+		StaleSince: d.StaleSince,
+		CreatedAt:  d.CreatedAt,
+	}
+}
+
+type listDevicesResponse struct {
+	Data []deviceResponse `json:"data"`
+}
+
+type statusTransitionResponse struct {
+	ID         string    `json:"id"`
+	DeviceID   string    `json:"device_id"`
+	RoutineID  string    `json:"routine_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	Kind       string    `json:"kind"`
+	Area       string    `json:"area"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+func newStatusTransitionResponse(t device.StatusTransition) statusTransitionResponse {
+	return statusTransitionResponse{
+		ID:         t.ID,
+		DeviceID:   t.DeviceID,
+		RoutineID:  t.RoutineID,
+		FromStatus: t.FromStatus,
+		ToStatus:   t.ToStatus,
+		Kind:       string(t.Kind),
+		Area:       t.Area,
+		OccurredAt: t.OccurredAt,
+	}
+}
+
+type listStatusTransitionsResponse struct {
+	Data []statusTransitionResponse `json:"data"`
+}
+
+type routineResponse struct {
+	ID           string    `json:"id"`
+	MessageID    string    `json:"message_id"`
+	TraceID      string    `json:"trace_id"`
+	DeviceID     string    `json:"device_id"`
+	Status       string    `json:"status"`
+	Context      string    `json:"context"`
+	Area         string    `json:"area"`
+	Diagnostics  string    `json:"diagnostics"`
+	DispatchedAt time.Time `json:"dispatched_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func newRoutineResponse(r device.Routine) routineResponse {
+	return routineResponse{
+		ID:           r.ID,
+		MessageID:    r.MessageID,
+		TraceID:      r.TraceID,
+		DeviceID:     r.DeviceID,
+		Status:       r.Status,
+		Context:      r.Context,
+		Area:         r.Area,
+		Diagnostics:  r.Diagnostics,
+		DispatchedAt: r.DispatchedAt,
+		CreatedAt:    r.CreatedAt,
+	}
+}
+
+type listRoutinesResponse struct {
+	Data       []routineResponse `json:"data"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		instrumentation.Logger.Error("Failed to encode response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
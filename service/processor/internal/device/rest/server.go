@@ -0,0 +1,34 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/charmingruby/devicio/service/processor/pkg/instrumentation"
+)
+
+const readHeaderTimeout = 5 * time.Second
+
+func NewServer(port string, handler *Handler) *http.Server {
+	mux := http.NewServeMux()
+	handler.Register(mux)
+
+	return &http.Server{
+		Addr:              ":" + port,
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+}
+
+// extractContext continues the caller's trace when the request carries W3C
+// trace context headers.
+func extractContext(r *http.Request) context.Context {
+	carrier := make(map[string]string, len(r.Header))
+	for k := range r.Header {
+		carrier[strings.ToLower(k)] = r.Header.Get(k)
+	}
+
+	return instrumentation.Tracer.Extract(r.Context(), carrier)
+}
@@ -2,106 +2,330 @@ package device
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/charmingruby/devicio/lib/core/id"
 	"github.com/charmingruby/devicio/lib/messaging"
-	"github.com/charmingruby/devicio/lib/messaging/rabbitmq"
 	"github.com/charmingruby/devicio/lib/proto/gen/pb"
-	"github.com/charmingruby/devicio/service/processor/internal/device/client"
+	"github.com/charmingruby/devicio/lib/resilience"
+	"github.com/charmingruby/devicio/service/processor/internal/alert"
 	"github.com/charmingruby/devicio/service/processor/pkg/instrumentation"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+var ErrUnexpectedMessageType = errors.New("unexpected message type")
+
+type RoutinePage struct {
+	Routines []Routine
+	// NextCursor is the After value for the next page, empty on the last one.
+	NextCursor string
+}
+
 type Service struct {
 	queue       messaging.Queue
+	events      messaging.Queue
 	repo        RoutineRepository
-	externalAPI client.UnstableAPI
+	devices     DeviceRepository
+	externalAPI ExternalAPI
+	alerts      *alert.Engine
 }
 
-func NewService(queue *rabbitmq.Client, repo RoutineRepository, externalAPI client.UnstableAPI) *Service {
+func NewService(
+	queue, events messaging.Queue,
+	repo RoutineRepository,
+	devices DeviceRepository,
+	externalAPI ExternalAPI,
+	alerts *alert.Engine,
+) *Service {
 	return &Service{
 		queue:       queue,
+		events:      events,
 		repo:        repo,
+		devices:     devices,
 		externalAPI: externalAPI,
+		alerts:      alerts,
 	}
 }
 
-func (s *Service) ProcessRoutine(ctx context.Context, msg []byte) error {
+func (s *Service) ProcessRoutine(ctx context.Context, msg messaging.Message) error {
 	ctx, complete := instrumentation.Tracer.Span(ctx, "service.Service.ProcessRoutine")
 	defer complete()
 
 	traceID := instrumentation.Tracer.GetTraceIDFromContext(ctx)
 
-	instrumentation.Logger.Debug("Starting to process routine", "traceId", traceID)
+	instrumentation.Logger.Debug("Starting to process routine",
+		"traceId", traceID,
+		"messageId", msg.ID,
+		"producer", msg.Producer,
+		"schemaVersion", msg.SchemaVersion,
+	)
+
+	if !msg.PublishedAt.IsZero() {
+		brokerDwellTimeMetric, err := instrumentation.BrokerDwellTimeHistogramMetric()
+		if err != nil {
+			instrumentation.Logger.Error("Failed to get broker dwell time histogram metric", "error", err)
+		} else {
+			brokerDwellTimeMetric.Observe(time.Since(msg.PublishedAt).Seconds())
+		}
+	}
 
 	r, ctx, err := s.parseProcessRoutineData(ctx, msg)
 	if err != nil {
 		instrumentation.Logger.Error("Failed to parse process routine data", "error", err)
 
-		errorsCounterListMetric, err := instrumentation.ErrorsCounterListMetric()
-		if err != nil {
-			instrumentation.Logger.Error("Failed to get errors counter list metric", "error", err)
+		errorsCounterListMetric, metricErr := instrumentation.ErrorsCounterListMetric()
+		if metricErr != nil {
+			instrumentation.Logger.Error("Failed to get errors counter list metric", "error", metricErr)
+		} else {
+			errorsCounterListMetric.WithLabelValues("parse_error").Inc()
 		}
 
-		errorsCounterListMetric.WithLabelValues("parse_error").Inc()
 		return err
 	}
 
+	existing, ctx, err := s.repo.FindByMessageID(ctx, r.MessageID)
+	if err != nil && !errors.Is(err, ErrRoutineNotFound) {
+		instrumentation.Logger.Error("Failed to check routine idempotency", "error", err)
+
+		errorsCounterListMetric, metricErr := instrumentation.ErrorsCounterListMetric()
+		if metricErr != nil {
+			instrumentation.Logger.Error("Failed to get errors counter list metric", "error", metricErr)
+		} else {
+			errorsCounterListMetric.WithLabelValues("store_error").Inc()
+		}
+
+		return err
+	}
+
+	if err == nil {
+		if existing.SightedAt != nil {
+			s.skipDuplicate(traceID, r.MessageID)
+			return nil
+		}
+
+		// A previous delivery stored the routine but failed before recording
+		// its sighting, so resume from there rather than dropping it.
+		instrumentation.Logger.Info("Resuming stored routine without a sighting", "traceId", traceID, "routineId", existing.ID)
+
+		return s.recordSighting(ctx, traceID, existing)
+	}
+
 	instrumentation.Logger.Debug("Processing routine", "traceId", traceID, "routineId", r.ID)
 
 	ctx, err = s.externalAPI.VolatileCall(ctx)
 	if err != nil {
 		instrumentation.Logger.Error("Failed to call external API", "error", err)
 
-		errorsCounterListMetric, err := instrumentation.ErrorsCounterListMetric()
-		if err != nil {
-			instrumentation.Logger.Error("Failed to get errors counter list metric", "error", err)
+		errorType := "api_error"
+		if errors.Is(err, resilience.ErrCircuitOpen) {
+			errorType = "circuit_open_error"
+		}
+
+		errorsCounterListMetric, metricErr := instrumentation.ErrorsCounterListMetric()
+		if metricErr != nil {
+			instrumentation.Logger.Error("Failed to get errors counter list metric", "error", metricErr)
+		} else {
+			errorsCounterListMetric.WithLabelValues(errorType).Inc()
 		}
 
-		errorsCounterListMetric.WithLabelValues("api_error").Inc()
 		return err
 	}
 
 	instrumentation.Logger.Debug("External API call completed", "traceId", traceID)
 
-	if _, err := s.repo.Store(ctx, r); err != nil {
+	// The device must exist before the routine that references it is stored.
+	ctx, err = s.devices.Register(ctx, Device{
+		ID:         r.DeviceID,
+		Status:     r.Status,
+		Area:       r.Area,
+		LastSeenAt: r.DispatchedAt,
+	})
+	if err != nil {
+		instrumentation.Logger.Error("Failed to register device", "error", err)
+
+		errorsCounterListMetric, metricErr := instrumentation.ErrorsCounterListMetric()
+		if metricErr != nil {
+			instrumentation.Logger.Error("Failed to get errors counter list metric", "error", metricErr)
+		} else {
+			errorsCounterListMetric.WithLabelValues("store_error").Inc()
+		}
+
+		return err
+	}
+
+	stored, _, err := s.repo.Store(ctx, r)
+	if err != nil {
+		// A concurrent redelivery may have stored the routine while this one
+		// was waiting on the external API.
+		if errors.Is(err, ErrRoutineAlreadyProcessed) {
+			s.skipDuplicate(traceID, r.MessageID)
+			return nil
+		}
+
 		instrumentation.Logger.Error("Failed to store routine", "error", err)
 
-		errorsCounterListMetric, err := instrumentation.ErrorsCounterListMetric()
-		if err != nil {
-			instrumentation.Logger.Error("Failed to get errors counter list metric", "error", err)
+		errorsCounterListMetric, metricErr := instrumentation.ErrorsCounterListMetric()
+		if metricErr != nil {
+			instrumentation.Logger.Error("Failed to get errors counter list metric", "error", metricErr)
+		} else {
+			errorsCounterListMetric.WithLabelValues("store_error").Inc()
 		}
 
-		errorsCounterListMetric.WithLabelValues("store_error").Inc()
 		return err
 	}
 
-	instrumentation.Logger.Debug("Stored routine", "traceId", traceID, "routineId", r.ID)
+	instrumentation.Logger.Debug("Stored routine", "traceId", traceID, "routineId", stored.ID, "createdAt", stored.CreatedAt)
+
+	return s.recordSighting(ctx, traceID, stored)
+}
+
+// recordSighting records the device sighting of a stored routine, publishes
+// the status transition it caused and evaluates alert rules against it.
+func (s *Service) recordSighting(ctx context.Context, traceID string, stored Routine) error {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "service.Service.recordSighting")
+	defer complete()
+
+	// The sighting is recorded only once the routine is stored, so its status
+	// transition never references a missing routine and is not published
+	// again when a failed delivery is retried.
+	sighting, ctx, err := s.devices.RecordSighting(ctx, Device{
+		ID:         stored.DeviceID,
+		Status:     stored.Status,
+		Area:       stored.Area,
+		LastSeenAt: stored.DispatchedAt,
+	}, stored.ID)
+	if err != nil {
+		instrumentation.Logger.Error("Failed to record device sighting", "error", err)
+
+		errorsCounterListMetric, metricErr := instrumentation.ErrorsCounterListMetric()
+		if metricErr != nil {
+			instrumentation.Logger.Error("Failed to get errors counter list metric", "error", metricErr)
+		} else {
+			errorsCounterListMetric.WithLabelValues("store_error").Inc()
+		}
+
+		return err
+	}
+
+	instrumentation.Logger.Debug("Registered device sighting", "traceId", traceID, "deviceId", sighting.Device.ID, "lastSeenAt", sighting.Device.LastSeenAt)
+
+	if sighting.Transition != nil {
+		ctx = s.publishStatusChanged(ctx, *sighting.Transition)
+	}
+
+	s.alerts.Evaluate(ctx, alert.Observation{
+		DeviceID:  stored.DeviceID,
+		RoutineID: stored.ID,
+		Status:    stored.Status,
+		Area:      stored.Area,
+	})
 
 	// messageProcessedCounterMetric, err := instrumentation.MessagesProcessedCounterMetric()
 	// if err != nil {
 	// 	instrumentation.Logger.Error("Failed to get messages processed counter metric", "error", err)
 	// }
 
-	// messageProcessedCounterMetric.WithLabelValues(r.ID).Inc()
+	// messageProcessedCounterMetric.WithLabelValues(stored.ID).Inc()
 
 	return nil
 }
 
-func (s *Service) parseProcessRoutineData(ctx context.Context, b []byte) (Routine, context.Context, error) {
+func (s *Service) GetRoutine(ctx context.Context, id string) (Routine, error) {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "service.Service.GetRoutine")
+	defer complete()
+
+	r, _, err := s.repo.FindByID(ctx, id)
+
+	return r, err
+}
+
+func (s *Service) GetDevice(ctx context.Context, id string) (Device, error) {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "service.Service.GetDevice")
+	defer complete()
+
+	d, _, err := s.devices.FindByID(ctx, id)
+
+	return d, err
+}
+
+func (s *Service) ListStaleDevices(ctx context.Context) ([]Device, error) {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "service.Service.ListStaleDevices")
+	defer complete()
+
+	devices, _, err := s.devices.ListStale(ctx)
+
+	return devices, err
+}
+
+func (s *Service) ListStatusTransitions(ctx context.Context, deviceID string, limit int) ([]StatusTransition, error) {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "service.Service.ListStatusTransitions")
+	defer complete()
+
+	switch {
+	case limit <= 0:
+		limit = defaultListLimit
+	case limit > maxListLimit:
+		limit = maxListLimit
+	}
+
+	transitions, _, err := s.devices.ListTransitions(ctx, deviceID, limit)
+
+	return transitions, err
+}
+
+func (s *Service) ListRoutines(ctx context.Context, filter RoutineFilter) (RoutinePage, error) {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "service.Service.ListRoutines")
+	defer complete()
+
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = defaultListLimit
+	case filter.Limit > maxListLimit:
+		filter.Limit = maxListLimit
+	}
+
+	routines, _, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return RoutinePage{}, err
+	}
+
+	page := RoutinePage{Routines: routines}
+	if len(routines) == filter.Limit {
+		page.NextCursor = routines[len(routines)-1].ID
+	}
+
+	return page, nil
+}
+
+func (s *Service) parseProcessRoutineData(ctx context.Context, msg messaging.Message) (Routine, context.Context, error) {
 	ctx, complete := instrumentation.Tracer.Span(ctx, "service.Service.parseProcessRoutineData")
 	defer complete()
 
 	var p pb.DeviceRoutine
 
-	if err := proto.Unmarshal(b, &p); err != nil {
+	if msg.Type != "" && msg.Type != string(proto.MessageName(&p)) {
+		return Routine{}, ctx, fmt.Errorf("%w: %s", ErrUnexpectedMessageType, msg.Type)
+	}
+
+	if err := msg.Unmarshal(&p); err != nil {
 		return Routine{}, ctx, err
 	}
 
 	r := Routine{}
 
 	r.ID = id.New()
+	r.MessageID = idempotencyKey(msg)
+	r.TraceID = instrumentation.Tracer.GetTraceIDFromContext(ctx)
 	r.DeviceID = p.GetId()
 	r.Status = p.GetStatus().String()
 	r.Context = p.GetContext()
@@ -112,3 +336,65 @@ func (s *Service) parseProcessRoutineData(ctx context.Context, b []byte) (Routin
 
 	return r, ctx, nil
 }
+
+// publishStatusChanged emits a DeviceStatusChanged event for a recorded
+// transition. The transition is already committed, so a failed publish is
+// logged and counted rather than failing the routine: a redelivery would not
+// detect the transition again.
+func (s *Service) publishStatusChanged(ctx context.Context, t StatusTransition) context.Context {
+	ctx, complete := instrumentation.Tracer.Span(ctx, "service.Service.publishStatusChanged")
+	defer complete()
+
+	instrumentation.Logger.Info("Device status changed",
+		"deviceId", t.DeviceID,
+		"from", t.FromStatus,
+		"to", t.ToStatus,
+		"kind", t.Kind,
+	)
+
+	ctx, err := s.events.Publish(ctx, &pb.DeviceStatusChanged{
+		DeviceId:       t.DeviceID,
+		PreviousStatus: pb.DeviceStatus(pb.DeviceStatus_value[t.FromStatus]),
+		CurrentStatus:  pb.DeviceStatus(pb.DeviceStatus_value[t.ToStatus]),
+		Area:           t.Area,
+		RoutineId:      t.RoutineID,
+		ChangedAt:      timestamppb.New(t.OccurredAt),
+	})
+	if err != nil {
+		instrumentation.Logger.Error("Failed to publish device status changed event", "error", err, "deviceId", t.DeviceID)
+
+		errorsCounterListMetric, err := instrumentation.ErrorsCounterListMetric()
+		if err != nil {
+			instrumentation.Logger.Error("Failed to get errors counter list metric", "error", err)
+			return ctx
+		}
+
+		errorsCounterListMetric.WithLabelValues("publish_error").Inc()
+	}
+
+	return ctx
+}
+
+func (s *Service) skipDuplicate(traceID, messageID string) {
+	instrumentation.Logger.Info("Skipping already processed routine", "traceId", traceID, "messageId", messageID)
+
+	duplicatesSkippedCounterMetric, err := instrumentation.DuplicatesSkippedCounterMetric()
+	if err != nil {
+		instrumentation.Logger.Error("Failed to get duplicates skipped counter metric", "error", err)
+		return
+	}
+
+	duplicatesSkippedCounterMetric.Inc()
+}
+
+// idempotencyKey identifies a routine across redeliveries. Messages without an
+// envelope ID fall back to a hash of their body.
+func idempotencyKey(msg messaging.Message) string {
+	if msg.ID != "" {
+		return msg.ID
+	}
+
+	sum := sha256.Sum256(msg.Body)
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,251 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/charmingruby/devicio/lib/messaging"
+	"github.com/charmingruby/devicio/lib/proto/gen/pb"
+	"github.com/charmingruby/devicio/service/processor/internal/alert"
+	"github.com/charmingruby/devicio/service/processor/pkg/instrumentation"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type nopTracer struct{}
+
+func (nopTracer) Span(ctx context.Context, _ string) (context.Context, func()) {
+	return ctx, func() {}
+}
+
+func (nopTracer) GetTraceIDFromContext(context.Context) string { return "" }
+
+func (nopTracer) Inject(context.Context, map[string]string) {}
+
+func (nopTracer) Extract(ctx context.Context, _ map[string]string) context.Context {
+	return ctx
+}
+
+func (nopTracer) Close() error { return nil }
+
+func TestMain(m *testing.M) {
+	instrumentation.NewLogger("error")
+	instrumentation.NewMeter()
+	instrumentation.Tracer = nopTracer{}
+
+	os.Exit(m.Run())
+}
+
+// fakeRoutineRepository keeps routines in memory, keyed by message ID.
+type fakeRoutineRepository struct {
+	mu       sync.Mutex
+	routines map[string]Routine
+}
+
+func newFakeRoutineRepository() *fakeRoutineRepository {
+	return &fakeRoutineRepository{routines: make(map[string]Routine)}
+}
+
+func (r *fakeRoutineRepository) Store(ctx context.Context, routine Routine) (Routine, context.Context, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.routines[routine.MessageID]; ok {
+		return Routine{}, ctx, ErrRoutineAlreadyProcessed
+	}
+
+	r.routines[routine.MessageID] = routine
+
+	return routine, ctx, nil
+}
+
+func (r *fakeRoutineRepository) FindByMessageID(ctx context.Context, messageID string) (Routine, context.Context, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	routine, ok := r.routines[messageID]
+	if !ok {
+		return Routine{}, ctx, ErrRoutineNotFound
+	}
+
+	return routine, ctx, nil
+}
+
+func (r *fakeRoutineRepository) FindByID(ctx context.Context, id string) (Routine, context.Context, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, routine := range r.routines {
+		if routine.ID == id {
+			return routine, ctx, nil
+		}
+	}
+
+	return Routine{}, ctx, ErrRoutineNotFound
+}
+
+func (r *fakeRoutineRepository) List(ctx context.Context, _ RoutineFilter) ([]Routine, context.Context, error) {
+	return nil, ctx, nil
+}
+
+func (r *fakeRoutineRepository) markSighted(routineID string, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for messageID, routine := range r.routines {
+		if routine.ID == routineID {
+			routine.SightedAt = &at
+			r.routines[messageID] = routine
+		}
+	}
+}
+
+// fakeDeviceRepository records sightings and marks their routines as sighted,
+// failing the first failSightings calls to RecordSighting.
+type fakeDeviceRepository struct {
+	routines *fakeRoutineRepository
+
+	mu            sync.Mutex
+	failSightings int
+	sightings     []string
+}
+
+func (r *fakeDeviceRepository) Register(ctx context.Context, _ Device) (context.Context, error) {
+	return ctx, nil
+}
+
+func (r *fakeDeviceRepository) RecordSighting(ctx context.Context, d Device, routineID string) (Sighting, context.Context, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.failSightings > 0 {
+		r.failSightings--
+		return Sighting{}, ctx, errors.New("connection reset")
+	}
+
+	r.sightings = append(r.sightings, routineID)
+	r.routines.markSighted(routineID, time.Now())
+
+	return Sighting{Device: d}, ctx, nil
+}
+
+func (r *fakeDeviceRepository) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.sightings...)
+}
+
+func (r *fakeDeviceRepository) FindByID(ctx context.Context, _ string) (Device, context.Context, error) {
+	return Device{}, ctx, ErrDeviceNotFound
+}
+
+func (r *fakeDeviceRepository) ListTransitions(ctx context.Context, _ string, _ int) ([]StatusTransition, context.Context, error) {
+	return nil, ctx, nil
+}
+
+func (r *fakeDeviceRepository) ListStaleCandidates(ctx context.Context, _ time.Time) ([]Device, context.Context, error) {
+	return nil, ctx, nil
+}
+
+func (r *fakeDeviceRepository) MarkStale(ctx context.Context, _ string, _, _ time.Time) (Device, bool, context.Context, error) {
+	return Device{}, false, ctx, nil
+}
+
+func (r *fakeDeviceRepository) ListStale(ctx context.Context) ([]Device, context.Context, error) {
+	return nil, ctx, nil
+}
+
+type countingExternalAPI struct {
+	calls int
+}
+
+func (a *countingExternalAPI) VolatileCall(ctx context.Context) (context.Context, error) {
+	a.calls++
+	return ctx, nil
+}
+
+func newTestService(t *testing.T) (*Service, *fakeRoutineRepository, *fakeDeviceRepository, *countingExternalAPI) {
+	t.Helper()
+
+	routines := newFakeRoutineRepository()
+	devices := &fakeDeviceRepository{routines: routines}
+	api := &countingExternalAPI{}
+
+	alerts := alert.NewEngine(alert.EngineConfig{}, nil, nil)
+	t.Cleanup(alerts.Close)
+
+	return NewService(nil, nil, routines, devices, api, alerts), routines, devices, api
+}
+
+func newRoutineMessage(t *testing.T) messaging.Message {
+	t.Helper()
+
+	msg, err := messaging.NewMessage(&pb.DeviceRoutine{
+		Id:           "device-1",
+		Status:       pb.DeviceStatus_HEALTHY,
+		Area:         "north",
+		DispatchedAt: timestamppb.Now(),
+	}, "test")
+	if err != nil {
+		t.Fatalf("NewMessage: %v", err)
+	}
+
+	return msg
+}
+
+func TestProcessRoutineResumesSightingOnRedelivery(t *testing.T) {
+	svc, routines, devices, api := newTestService(t)
+	devices.failSightings = 1
+
+	msg := newRoutineMessage(t)
+
+	if err := svc.ProcessRoutine(context.Background(), msg); err == nil {
+		t.Fatalf("ProcessRoutine succeeded, want the sighting failure")
+	}
+
+	stored, _, err := routines.FindByMessageID(context.Background(), msg.ID)
+	if err != nil {
+		t.Fatalf("routine was not stored: %v", err)
+	}
+
+	if got := devices.recorded(); len(got) != 0 {
+		t.Fatalf("sightings after failure = %v, want none", got)
+	}
+
+	// the broker redelivers the message after the failed attempt
+	if err := svc.ProcessRoutine(context.Background(), msg); err != nil {
+		t.Fatalf("ProcessRoutine redelivery: %v", err)
+	}
+
+	if got := devices.recorded(); len(got) != 1 || got[0] != stored.ID {
+		t.Fatalf("sightings after redelivery = %v, want [%s]", got, stored.ID)
+	}
+
+	if api.calls != 1 {
+		t.Fatalf("external API calls = %d, want 1", api.calls)
+	}
+}
+
+func TestProcessRoutineSkipsSightedRedelivery(t *testing.T) {
+	svc, _, devices, api := newTestService(t)
+
+	msg := newRoutineMessage(t)
+
+	for range 2 {
+		if err := svc.ProcessRoutine(context.Background(), msg); err != nil {
+			t.Fatalf("ProcessRoutine: %v", err)
+		}
+	}
+
+	if got := devices.recorded(); len(got) != 1 {
+		t.Fatalf("sightings = %v, want exactly one", got)
+	}
+
+	if api.calls != 1 {
+		t.Fatalf("external API calls = %d, want 1", api.calls)
+	}
+}
@@ -0,0 +1,69 @@
+package device
+
+import (
+	"time"
+
+	"github.com/charmingruby/devicio/lib/core/id"
+	"github.com/charmingruby/devicio/lib/proto/gen/pb"
+)
+
+type TransitionKind string
+
+const (
+	// TransitionDegraded moves a device to a more severe status.
+	TransitionDegraded TransitionKind = "degraded"
+	// TransitionImproved moves a device to a less severe, still unhealthy
+	// status.
+	TransitionImproved TransitionKind = "improved"
+	// TransitionRecovered moves a device back to HEALTHY.
+	TransitionRecovered TransitionKind = "recovered"
+)
+
+type StatusTransition struct {
+	ID         string         `db:"id"`
+	DeviceID   string         `db:"device_id"`
+	RoutineID  string         `db:"routine_id"`
+	FromStatus string         `db:"from_status"`
+	ToStatus   string         `db:"to_status"`
+	Kind       TransitionKind `db:"kind"`
+	Area       string         `db:"area"`
+	OccurredAt time.Time      `db:"occurred_at"`
+	CreatedAt  time.Time      `db:"created_at"`
+}
+
+// Sighting is the outcome of recording a routine against its device.
+// Transition is nil when the device was unknown or its status is unchanged.
+type Sighting struct {
+	Device     Device
+	Transition *StatusTransition
+}
+
+// DetectTransition compares a device's status before and after a routine.
+// Statuses are ranked by their DeviceStatus value, from HEALTHY to CRITICAL.
+// A device seen for the first time has no previous status and no transition.
+func DetectTransition(previousStatus string, current Device, routineID string) (StatusTransition, bool) {
+	if previousStatus == "" || previousStatus == current.Status {
+		return StatusTransition{}, false
+	}
+
+	kind := TransitionImproved
+
+	switch {
+	case current.Status == pb.DeviceStatus_HEALTHY.String():
+		kind = TransitionRecovered
+	case pb.DeviceStatus_value[current.Status] > pb.DeviceStatus_value[previousStatus]:
+		kind = TransitionDegraded
+	}
+
+	return StatusTransition{
+		ID:         id.New(),
+		DeviceID:   current.ID,
+		RoutineID:  routineID,
+		FromStatus: previousStatus,
+		ToStatus:   current.Status,
+		Kind:       kind,
+		Area:       current.Area,
+		OccurredAt: current.LastSeenAt,
+		CreatedAt:  time.Now().UTC(),
+	}, true
+}
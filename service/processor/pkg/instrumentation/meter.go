@@ -22,12 +22,24 @@ func NewMeter() {
 		Namespace: "devicio",
 	})
 
+	Meter.NewCounter(observability.CounterInput{
+		Name:      "duplicates_skipped",
+		Help:      "Total number of redelivered messages skipped because they were already processed",
+		Namespace: "devicio",
+	})
+
 	Meter.NewHistogram(observability.HistogramInput{
 		Name:      "processing_time",
 		Help:      "Time taken to process messages in seconds",
 		Namespace: "devicio",
 	})
 
+	Meter.NewHistogram(observability.HistogramInput{
+		Name:      "broker_dwell_time",
+		Help:      "Time messages spent in the broker between publish and consumption in seconds",
+		Namespace: "devicio",
+	})
+
 	Meter.NewCounterList(observability.CounterListInput{
 		CounterInput: observability.CounterInput{
 			Name:      "errors",
@@ -36,6 +48,39 @@ func NewMeter() {
 		},
 		LabelNames: []string{"error_type"},
 	})
+
+	Meter.NewGauge(observability.GaugeInput{
+		Name:      "stale_devices",
+		Help:      "Number of devices flagged as silent beyond their heartbeat threshold",
+		Namespace: "devicio",
+	})
+
+	Meter.NewGauge(observability.GaugeInput{
+		Name:      "external_api_circuit_state",
+		Help:      "State of the external API circuit breaker: 0 closed, 1 half-open, 2 open",
+		Namespace: "devicio",
+	})
+
+	Meter.NewCounter(observability.CounterInput{
+		Name:      "external_api_retries",
+		Help:      "Total number of external API calls retried after a transient failure",
+		Namespace: "devicio",
+	})
+
+	Meter.NewCounterList(observability.CounterListInput{
+		CounterInput: observability.CounterInput{
+			Name:      "alerts",
+			Help:      "Total number of alert notifications by rule and state",
+			Namespace: "devicio",
+		},
+		LabelNames: []string{"rule", "state"},
+	})
+
+	Meter.NewCounter(observability.CounterInput{
+		Name:      "alerts_dropped",
+		Help:      "Total number of firing alerts dropped because the notification buffer was full",
+		Namespace: "devicio",
+	})
 }
 
 func RunMetricsServer(port string) error {
@@ -56,6 +101,15 @@ func ProcessingTimeHistogramMetric() (*prometheus.HistogramVec, error) {
 	return metric.(*prometheus.HistogramVec), nil
 }
 
+func BrokerDwellTimeHistogramMetric() (prometheus.Histogram, error) {
+	metric, err := Meter.GetMetric("broker_dwell_time", observability.HistogramMetricType)
+	if err != nil {
+		return nil, err
+	}
+
+	return metric.(prometheus.Histogram), nil
+}
+
 func MessagesProcessedCounterMetric() (prometheus.Counter, error) {
 	metric, err := Meter.GetMetric("messages_processed", observability.CounterMetricType)
 	if err != nil {
@@ -65,6 +119,15 @@ func MessagesProcessedCounterMetric() (prometheus.Counter, error) {
 	return metric.(prometheus.Counter), nil
 }
 
+func DuplicatesSkippedCounterMetric() (prometheus.Counter, error) {
+	metric, err := Meter.GetMetric("duplicates_skipped", observability.CounterMetricType)
+	if err != nil {
+		return nil, err
+	}
+
+	return metric.(prometheus.Counter), nil
+}
+
 func ErrorsCounterListMetric() (*prometheus.CounterVec, error) {
 	metric, err := Meter.GetMetric("errors", observability.CounterListMetricType)
 	if err != nil {
@@ -73,3 +136,48 @@ func ErrorsCounterListMetric() (*prometheus.CounterVec, error) {
 
 	return metric.(*prometheus.CounterVec), nil
 }
+
+func AlertsCounterListMetric() (*prometheus.CounterVec, error) {
+	metric, err := Meter.GetMetric("alerts", observability.CounterListMetricType)
+	if err != nil {
+		return nil, err
+	}
+
+	return metric.(*prometheus.CounterVec), nil
+}
+
+func AlertsDroppedCounterMetric() (prometheus.Counter, error) {
+	metric, err := Meter.GetMetric("alerts_dropped", observability.CounterMetricType)
+	if err != nil {
+		return nil, err
+	}
+
+	return metric.(prometheus.Counter), nil
+}
+
+func StaleDevicesGaugeMetric() (prometheus.Gauge, error) {
+	metric, err := Meter.GetMetric("stale_devices", observability.GaugeMetricType)
+	if err != nil {
+		return nil, err
+	}
+
+	return metric.(prometheus.Gauge), nil
+}
+
+func ExternalAPICircuitStateGaugeMetric() (prometheus.Gauge, error) {
+	metric, err := Meter.GetMetric("external_api_circuit_state", observability.GaugeMetricType)
+	if err != nil {
+		return nil, err
+	}
+
+	return metric.(prometheus.Gauge), nil
+}
+
+func ExternalAPIRetriesCounterMetric() (prometheus.Counter, error) {
+	metric, err := Meter.GetMetric("external_api_retries", observability.CounterMetricType)
+	if err != nil {
+		return nil, err
+	}
+
+	return metric.(prometheus.Counter), nil
+}